@@ -1,32 +1,135 @@
 package configs
 
 import (
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
 )
 
+const (
+	STORAGE_GCS   string = "gcs"
+	STORAGE_LOCAL string = "local"
+	STORAGE_S3    string = "s3"
+)
+
 type Config struct {
-	DB_MASTER          string `mapstructure:"DB_MASTER"`
-	GCS_BUCKET_NAME    string `mapstructure:"GCS_BUCKET_NAME"`
-	GOOGLE_PROJECT_ID  string `mapstructure:"GOOGLE_PROJECT_ID"`
-	GOOGLE_STORAGE_URL string `mapstructure:"GOOGLE_STORAGE_URL"`
-	RABBITMQ_URI       string `mapstructure:"RABBITMQ_URI"`
-	QUEUE_NAME         string `mapstructure:"QUEUE_NAME"`
-	PORT               string `mapstructure:"PORT"`
+	DB_MASTER            string `mapstructure:"DB_MASTER"`
+	STORAGE_BACKEND      string `mapstructure:"STORAGE_BACKEND"`
+	GCS_BUCKET_NAME      string `mapstructure:"GCS_BUCKET_NAME"`
+	GOOGLE_PROJECT_ID    string `mapstructure:"GOOGLE_PROJECT_ID"`
+	GOOGLE_STORAGE_URL   string `mapstructure:"GOOGLE_STORAGE_URL"`
+	LOCAL_STORAGE_PATH   string `mapstructure:"LOCAL_STORAGE_PATH"`
+	LOCAL_STORAGE_BUCKET string `mapstructure:"LOCAL_STORAGE_BUCKET"`
+	LOCAL_STORAGE_URL    string `mapstructure:"LOCAL_STORAGE_URL"`
+	S3_ENDPOINT          string `mapstructure:"S3_ENDPOINT"`
+	S3_REGION            string `mapstructure:"S3_REGION"`
+	S3_ACCESS_KEY_ID     string `mapstructure:"S3_ACCESS_KEY_ID"`
+	S3_SECRET_ACCESS_KEY string `mapstructure:"S3_SECRET_ACCESS_KEY"`
+	S3_BUCKET_NAME       string `mapstructure:"S3_BUCKET_NAME"`
+	S3_USE_SSL           bool   `mapstructure:"S3_USE_SSL"`
+	S3_USE_PATH_STYLE    bool   `mapstructure:"S3_USE_PATH_STYLE"`
+	S3_PUBLIC_URL        string `mapstructure:"S3_PUBLIC_URL"`
+	RABBITMQ_URI         string `mapstructure:"RABBITMQ_URI"`
+	QUEUE_NAME           string `mapstructure:"QUEUE_NAME"`
+	PORT                 string `mapstructure:"PORT"`
+	STRIP_GPS_ON_UPLOAD  bool   `mapstructure:"STRIP_GPS_ON_UPLOAD"`
+	// upload limits, see imageserv.UploadImage
+	UPLOAD_MAX_BYTES       int64    `mapstructure:"UPLOAD_MAX_BYTES"`
+	UPLOAD_MAX_WIDTH       int64    `mapstructure:"UPLOAD_MAX_WIDTH"`
+	UPLOAD_MAX_HEIGHT      int64    `mapstructure:"UPLOAD_MAX_HEIGHT"`
+	UPLOAD_MAX_PIXELS      int64    `mapstructure:"UPLOAD_MAX_PIXELS"`
+	UPLOAD_ALLOWED_FORMATS []string `mapstructure:"UPLOAD_ALLOWED_FORMATS"`
+	// access tokens, see userserv.Login
+	ACCESS_TOKEN_TTL  time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	REFRESH_TOKEN_TTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	JWT_ISSUER        string        `mapstructure:"JWT_ISSUER"`
+	JWT_AUDIENCE      string        `mapstructure:"JWT_AUDIENCE"`
+	// PEM keys, see jwtutils.LoadKeySet
+	JWT_SIGNING_KEY_FILE string   `mapstructure:"JWT_SIGNING_KEY_FILE"`
+	JWT_VERIFY_KEY_FILES []string `mapstructure:"JWT_VERIFY_KEY_FILES"`
+	// signed urls, see SignedURLKey
+	SIGNED_URL_KEY         string        `mapstructure:"SIGNED_URL_KEY"`
+	SIGNED_URL_DEFAULT_TTL time.Duration `mapstructure:"SIGNED_URL_DEFAULT_TTL"`
+	SIGNED_URL_MAX_TTL     time.Duration `mapstructure:"SIGNED_URL_MAX_TTL"`
+	// api keys, see apikeyserv.CreateAPIKey
+	API_KEY_MAX_TTL time.Duration `mapstructure:"API_KEY_MAX_TTL"`
 }
 
-var config Config
+// image size and expiry limits, the config starts with them so code running
+// without LoadConfig, like tests, is still bounded
+const (
+	DEFAULT_UPLOAD_MAX_WIDTH  int64         = 12000
+	DEFAULT_UPLOAD_MAX_HEIGHT int64         = 12000
+	DEFAULT_UPLOAD_MAX_PIXELS int64         = 50_000_000
+	DEFAULT_API_KEY_MAX_TTL   time.Duration = 2 * 365 * 24 * time.Hour
+)
+
+var config = Config{
+	UPLOAD_MAX_WIDTH:  DEFAULT_UPLOAD_MAX_WIDTH,
+	UPLOAD_MAX_HEIGHT: DEFAULT_UPLOAD_MAX_HEIGHT,
+	UPLOAD_MAX_PIXELS: DEFAULT_UPLOAD_MAX_PIXELS,
+	API_KEY_MAX_TTL:   DEFAULT_API_KEY_MAX_TTL,
+}
 
 func LoadConfig() error {
 	viper.AutomaticEnv()
 	viper.BindEnv("SECRET_KEY")
 	viper.BindEnv("DB_MASTER")
+	viper.BindEnv("STORAGE_BACKEND")
 	viper.BindEnv("GCS_BUCKET_NAME")
 	viper.BindEnv("GOOGLE_PROJECT_ID")
 	viper.BindEnv("GOOGLE_STORAGE_URL")
+	viper.BindEnv("LOCAL_STORAGE_PATH")
+	viper.BindEnv("LOCAL_STORAGE_BUCKET")
+	viper.BindEnv("LOCAL_STORAGE_URL")
+	viper.BindEnv("S3_ENDPOINT")
+	viper.BindEnv("S3_REGION")
+	viper.BindEnv("S3_ACCESS_KEY_ID")
+	viper.BindEnv("S3_SECRET_ACCESS_KEY")
+	viper.BindEnv("S3_BUCKET_NAME")
+	viper.BindEnv("S3_USE_SSL")
+	viper.BindEnv("S3_USE_PATH_STYLE")
+	viper.BindEnv("S3_PUBLIC_URL")
 	viper.BindEnv("RABBITMQ_URI")
 	viper.BindEnv("QUEUE_NAME")
 	viper.BindEnv("PORT")
+	viper.BindEnv("STRIP_GPS_ON_UPLOAD")
+	viper.BindEnv("UPLOAD_MAX_BYTES")
+	viper.BindEnv("UPLOAD_MAX_WIDTH")
+	viper.BindEnv("UPLOAD_MAX_HEIGHT")
+	viper.BindEnv("UPLOAD_MAX_PIXELS")
+	viper.BindEnv("UPLOAD_ALLOWED_FORMATS")
+	viper.BindEnv("ACCESS_TOKEN_TTL")
+	viper.BindEnv("REFRESH_TOKEN_TTL")
+	viper.BindEnv("JWT_ISSUER")
+	viper.BindEnv("JWT_AUDIENCE")
+	viper.BindEnv("JWT_SIGNING_KEY_FILE")
+	viper.BindEnv("JWT_VERIFY_KEY_FILES")
+	viper.BindEnv("SIGNED_URL_KEY")
+	viper.BindEnv("SIGNED_URL_DEFAULT_TTL")
+	viper.BindEnv("SIGNED_URL_MAX_TTL")
+	viper.BindEnv("API_KEY_MAX_TTL")
+
+	viper.SetDefault("STORAGE_BACKEND", STORAGE_GCS)
+	viper.SetDefault("LOCAL_STORAGE_PATH", "./data")
+	viper.SetDefault("LOCAL_STORAGE_BUCKET", "images")
+	viper.SetDefault("LOCAL_STORAGE_URL", "/files")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("S3_USE_SSL", true)
+	viper.SetDefault("STRIP_GPS_ON_UPLOAD", true)
+	viper.SetDefault("UPLOAD_MAX_BYTES", 20<<20)
+	viper.SetDefault("UPLOAD_MAX_WIDTH", DEFAULT_UPLOAD_MAX_WIDTH)
+	viper.SetDefault("UPLOAD_MAX_HEIGHT", DEFAULT_UPLOAD_MAX_HEIGHT)
+	viper.SetDefault("UPLOAD_MAX_PIXELS", DEFAULT_UPLOAD_MAX_PIXELS)
+	viper.SetDefault("UPLOAD_ALLOWED_FORMATS", []string{"jpeg", "png", "gif", "webp", "bmp", "tiff"})
+	viper.SetDefault("ACCESS_TOKEN_TTL", 15*time.Minute)
+	viper.SetDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	viper.SetDefault("JWT_ISSUER", "image-processing-api")
+	viper.SetDefault("JWT_AUDIENCE", "image-processing-api")
+	viper.SetDefault("SIGNED_URL_DEFAULT_TTL", 15*time.Minute)
+	viper.SetDefault("SIGNED_URL_MAX_TTL", 7*24*time.Hour)
+	viper.SetDefault("API_KEY_MAX_TTL", DEFAULT_API_KEY_MAX_TTL)
 
 	if err := viper.Unmarshal(&config); err != nil {
 		return err
@@ -38,6 +141,28 @@ func GetConfig() *Config {
 	return &config
 }
 
+// StorageURL returns the public base url objects of the configured storage
+// backend are served from.
+func (c *Config) StorageURL() string {
+	switch c.STORAGE_BACKEND {
+	case STORAGE_LOCAL:
+		return c.LOCAL_STORAGE_URL
+	case STORAGE_S3:
+		return c.S3_PUBLIC_URL
+	default:
+		return c.GOOGLE_STORAGE_URL
+	}
+}
+
+// SignedURLKey returns the key signed urls are signed with, SECRET_KEY is
+// used when SIGNED_URL_KEY isn't set.
+func (c *Config) SignedURLKey() []byte {
+	if c.SIGNED_URL_KEY != "" {
+		return []byte(c.SIGNED_URL_KEY)
+	}
+	return []byte(viper.GetString("SECRET_KEY"))
+}
+
 func SetupDB(dbstr string) (*sqlx.DB, error) {
 	db, err := sqlx.Open("postgres", dbstr)
 	if err != nil {
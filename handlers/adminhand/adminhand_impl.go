@@ -0,0 +1,99 @@
+package adminhand
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ARF-DEV/image-processing-api/middleware"
+	"github.com/ARF-DEV/image-processing-api/services/adminserv"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type AdminHandlerImpl struct {
+	adminServ adminserv.AdminServ
+}
+
+func New(adminServ adminserv.AdminServ) AdminHandler {
+	return &AdminHandlerImpl{adminServ: adminServ}
+}
+
+func (h *AdminHandlerImpl) GetUsers(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := httputils.GetPageLimit(r, 1, 10)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	users, meta, err := h.adminServ.GetUsers(r.Context(), page, limit)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	httputils.SendResponse(w, httputils.Success, users, meta, nil)
+}
+
+func (h *AdminHandlerImpl) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+func (h *AdminHandlerImpl) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *AdminHandlerImpl) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	adminID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	id, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	user, err := h.adminServ.SetUserDisabled(r.Context(), adminID, id, disabled)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, user, nil, nil)
+}
+
+func (h *AdminHandlerImpl) GetImages(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := httputils.GetPageLimit(r, 1, 10)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	var ownerID int64
+	if ownerStr := r.URL.Query().Get("owner_id"); ownerStr != "" {
+		if ownerID, err = strconv.ParseInt(ownerStr, 10, 64); err != nil {
+			err = fmt.Errorf("%w: invalid owner_id", httputils.ErrBadRequest)
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
+			return
+		}
+	}
+
+	images, meta, err := h.adminServ.GetImages(r.Context(), ownerID, page, limit)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, images, meta, nil)
+}
+
+func (h *AdminHandlerImpl) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	id, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	if err := h.adminServ.DeleteImage(r.Context(), id); err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, nil, nil, nil)
+}
@@ -0,0 +1,11 @@
+package adminhand
+
+import "net/http"
+
+type AdminHandler interface {
+	GetUsers(w http.ResponseWriter, r *http.Request)
+	DisableUser(w http.ResponseWriter, r *http.Request)
+	EnableUser(w http.ResponseWriter, r *http.Request)
+	GetImages(w http.ResponseWriter, r *http.Request)
+	DeleteImage(w http.ResponseWriter, r *http.Request)
+}
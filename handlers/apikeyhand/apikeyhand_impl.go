@@ -0,0 +1,72 @@
+package apikeyhand
+
+import (
+	"net/http"
+
+	"github.com/ARF-DEV/image-processing-api/middleware"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/services/apikeyserv"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type APIKeyHandlerImpl struct {
+	apiKeyServ apikeyserv.APIKeyServ
+}
+
+func New(apiKeyServ apikeyserv.APIKeyServ) APIKeyHandler {
+	return &APIKeyHandlerImpl{apiKeyServ: apiKeyServ}
+}
+
+func (h *APIKeyHandlerImpl) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, err := middleware.GetPrincipal(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	req := model.CreateAPIKeyRequest{}
+	if err := httputils.ParseRequestBody(r, &req); err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	res, err := h.apiKeyServ.CreateAPIKey(r.Context(), principal, req)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, res, nil, nil)
+}
+
+func (h *APIKeyHandlerImpl) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	keys, err := h.apiKeyServ.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, keys, nil, nil)
+}
+
+func (h *APIKeyHandlerImpl) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	id, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	if err := h.apiKeyServ.RevokeAPIKey(r.Context(), userID, id); err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, nil, nil, nil)
+}
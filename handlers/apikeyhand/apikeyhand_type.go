@@ -0,0 +1,9 @@
+package apikeyhand
+
+import "net/http"
+
+type APIKeyHandler interface {
+	CreateAPIKey(w http.ResponseWriter, r *http.Request)
+	GetAPIKeys(w http.ResponseWriter, r *http.Request)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+}
@@ -3,26 +3,76 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/ARF-DEV/image-processing-api/handlers/adminhand"
+	"github.com/ARF-DEV/image-processing-api/handlers/apikeyhand"
 	"github.com/ARF-DEV/image-processing-api/handlers/imagehand"
+	"github.com/ARF-DEV/image-processing-api/handlers/jobhand"
+	"github.com/ARF-DEV/image-processing-api/handlers/jwkshand"
 	"github.com/ARF-DEV/image-processing-api/handlers/userhand"
 	"github.com/ARF-DEV/image-processing-api/middleware"
+	"github.com/ARF-DEV/image-processing-api/model"
 	"github.com/go-chi/chi/v5"
 )
 
-func CreateHandlers(user userhand.UserHandler, image imagehand.ImageHandler) http.Handler {
+// files serves stored objects under /files and may be nil when the storage
+// backend serves them itself.
+func CreateHandlers(auth *middleware.Authenticator, user userhand.UserHandler, image imagehand.ImageHandler, job jobhand.JobHandler, admin adminhand.AdminHandler, apiKey apikeyhand.APIKeyHandler, jwks jwkshand.JWKSHandler, files http.Handler) http.Handler {
 	r := chi.NewRouter()
 
+	r.Get("/.well-known/jwks.json", jwks.GetJWKS)
 	r.Post("/register", user.Register)
 	r.Post("/login", user.Login)
+	r.Post("/token/refresh", user.Refresh)
+	r.With(auth.Authenticate, middleware.RequireAccessToken).Post("/logout", user.Logout)
+
+	r.Route("/api-keys", func(r chi.Router) {
+		r.Use(auth.Authenticate, middleware.RequireAccessToken)
+		r.Get("/", apiKey.GetAPIKeys)
+		r.Post("/", apiKey.CreateAPIKey)
+		r.Delete("/{id}", apiKey.RevokeAPIKey)
+	})
+
+	read := middleware.RequireScope(model.SCOPE_IMAGES_READ)
+	write := middleware.RequireScope(model.SCOPE_IMAGES_WRITE)
+	transform := middleware.RequireScope(model.SCOPE_IMAGES_TRANSFORM)
 
 	r.Route("/images", func(r chi.Router) {
-		r.Use(middleware.Authenticate)
-		r.Get("/", image.GetImages)
-		r.Post("/", image.UploadImage)
+		r.Use(auth.Authenticate)
+		r.With(read).Get("/", image.GetImages)
+		r.With(write).Post("/", image.UploadImage)
+
+		r.With(read).Get("/{id}", image.GetImage)
+		r.With(read).Get("/{id}/metadata", image.GetImageMetadata)
+		r.With(read).Get("/{id}/render", image.RenderImage)
+		r.With(read).Get("/{id}/download", image.DownloadImage)
+		r.With(read).Post("/{id}/sign", image.SignImageURL)
+		r.With(transform).Post("/{id}/transform", image.TransformImage)
+	})
 
-		r.Get("/{id}", image.GetImage)
-		r.Post("/{id}/transform", image.TransformImage)
+	// urls minted by POST /images/{id}/sign
+	r.Route("/signed/images", func(r chi.Router) {
+		r.Use(auth.VerifySignature)
+		r.With(read).Get("/{id}/render", image.RenderImage)
+		r.With(read).Get("/{id}/download", image.DownloadImage)
 	})
 
+	r.Route("/jobs", func(r chi.Router) {
+		r.Use(auth.Authenticate)
+		r.With(transform).Get("/{id}", job.GetJob)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(auth.Authenticate, middleware.RequireAccessToken, middleware.RequireRole(model.ROLE_ADMIN))
+		r.Get("/users", admin.GetUsers)
+		r.Post("/users/{id}/disable", admin.DisableUser)
+		r.Post("/users/{id}/enable", admin.EnableUser)
+		r.Get("/images", admin.GetImages)
+		r.Delete("/images/{id}", admin.DeleteImage)
+	})
+
+	if files != nil {
+		r.Handle("/files/*", http.StripPrefix("/files", files))
+	}
+
 	return r
 }
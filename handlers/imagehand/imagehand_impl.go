@@ -1,13 +1,28 @@
 package imagehand
 
 import (
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
 
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/middleware"
 	"github.com/ARF-DEV/image-processing-api/model"
 	"github.com/ARF-DEV/image-processing-api/services/imageserv"
 	"github.com/ARF-DEV/image-processing-api/utils/httputils"
 )
 
+const (
+	multipartOverhead int64 = 1 << 20
+	// larger uploads are buffered in temporary files
+	multipartMemory int64 = 10 << 20
+
+	renderCacheControl string = "private, max-age=31536000"
+)
+
 type ImageHandlerImpl struct {
 	imageServ imageserv.ImageServ
 }
@@ -19,32 +34,56 @@ func New(imageServ imageserv.ImageServ) ImageHandler {
 }
 
 func (h *ImageHandlerImpl) UploadImage(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(1024); err != nil {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	// leave room for the other parts of the form, the file itself is
+	// checked by the service
+	maxBytes := configs.GetConfig().UPLOAD_MAX_BYTES
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = fmt.Errorf("%w: images can't be larger than %d bytes", httputils.ErrPayloadTooLarge, maxBytes)
+		} else {
+			err = fmt.Errorf("%w: %v", httputils.ErrBadRequest, err)
+		}
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
 	img, header, err := r.FormFile("image")
 	if err != nil {
+		err = fmt.Errorf("%w: %v", httputils.ErrBadRequest, err)
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
 	}
+	defer img.Close()
 
-	if err := h.imageServ.UploadImage(r.Context(), img, header); err != nil {
+	res, err := h.imageServ.UploadImage(r.Context(), userID, img, header)
+	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
 	}
 
-	httputils.SendResponse(w, httputils.Success, nil, nil, nil)
+	httputils.SendResponse(w, httputils.Success, res, nil, nil)
 }
 
 func (h *ImageHandlerImpl) GetImages(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
 	page, limit, err := httputils.GetPageLimit(r, 1, 10)
 	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
 	}
-	images, meta, err := h.imageServ.GetAllImage(r.Context(), page, limit)
+	images, meta, err := h.imageServ.GetAllImage(r.Context(), userID, page, limit)
 	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
@@ -54,13 +93,39 @@ func (h *ImageHandlerImpl) GetImages(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ImageHandlerImpl) GetImage(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	imageId, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	res, err := h.imageServ.GetImage(r.Context(), userID, imageId)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	httputils.SendResponse(w, httputils.Success, res, nil, nil)
+}
+
+func (h *ImageHandlerImpl) GetImageMetadata(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
 	imageId, err := httputils.GetURLParam[int64](r, "id")
 	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
 	}
 
-	res, err := h.imageServ.GetImage(r.Context(), imageId)
+	res, err := h.imageServ.GetImageMetadata(r.Context(), userID, imageId)
 	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
@@ -69,7 +134,107 @@ func (h *ImageHandlerImpl) GetImage(w http.ResponseWriter, r *http.Request) {
 	httputils.SendResponse(w, httputils.Success, res, nil, nil)
 }
 
+// RenderImage writes the rendered image itself instead of a JSON response,
+// errors are still JSON.
+func (h *ImageHandlerImpl) RenderImage(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	id, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	renderReq := model.RenderRequest{}
+	if err := httputils.ParseURLValues(r.URL.Query(), &renderReq); err != nil {
+		err = fmt.Errorf("%w: %v", httputils.ErrBadRequest, err)
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	rendered, err := h.imageServ.RenderImage(r.Context(), userID, id, renderReq)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	// the hash covers the source content and the steps, so a variant never
+	// changes
+	etag := fmt.Sprintf("%q", rendered.Hash)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", renderCacheControl)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/"+rendered.Format)
+	w.Header().Set("Content-Length", strconv.Itoa(len(rendered.Data)))
+	w.Write(rendered.Data)
+}
+
+func (h *ImageHandlerImpl) SignImageURL(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	id, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	signReq := model.SignURLRequest{}
+	if err := httputils.ParseRequestBody(r, &signReq); err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	res, err := h.imageServ.SignImageURL(r.Context(), userID, id, signReq)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	httputils.SendResponse(w, httputils.Success, res, nil, nil)
+}
+
+// DownloadImage writes the stored file as an attachment named after the
+// uploaded file.
+func (h *ImageHandlerImpl) DownloadImage(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	id, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	image, data, err := h.imageServ.DownloadImage(r.Context(), userID, id)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	fileName := image.OriginalFilename
+	if fileName == "" {
+		fileName = path.Base(image.GetObject())
+	}
+	w.Header().Set("Content-Type", "image/"+image.Format)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	w.Write(data)
+}
+
 func (h *ImageHandlerImpl) TransformImage(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
 	id, err := httputils.GetURLParam[int64](r, "id")
 	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
@@ -81,10 +246,10 @@ func (h *ImageHandlerImpl) TransformImage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = h.imageServ.TransformImageBroker(r.Context(), id, transformReq.Transform)
+	job, err := h.imageServ.TransformImageBroker(r.Context(), userID, id, transformReq.Transform)
 	if err != nil {
 		httputils.SendResponse(w, err.Error(), nil, nil, err)
 		return
 	}
-	httputils.SendResponse(w, httputils.Success, nil, nil, nil)
+	httputils.SendResponse(w, httputils.Success, job, nil, nil)
 }
@@ -6,5 +6,9 @@ type ImageHandler interface {
 	UploadImage(w http.ResponseWriter, r *http.Request)
 	GetImages(w http.ResponseWriter, r *http.Request)
 	GetImage(w http.ResponseWriter, r *http.Request)
+	GetImageMetadata(w http.ResponseWriter, r *http.Request)
+	RenderImage(w http.ResponseWriter, r *http.Request)
+	SignImageURL(w http.ResponseWriter, r *http.Request)
+	DownloadImage(w http.ResponseWriter, r *http.Request)
 	TransformImage(w http.ResponseWriter, r *http.Request)
 }
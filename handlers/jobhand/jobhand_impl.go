@@ -0,0 +1,38 @@
+package jobhand
+
+import (
+	"net/http"
+
+	"github.com/ARF-DEV/image-processing-api/middleware"
+	"github.com/ARF-DEV/image-processing-api/services/jobserv"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type JobHandlerImpl struct {
+	jobServ jobserv.JobServ
+}
+
+func New(jobServ jobserv.JobServ) JobHandler {
+	return &JobHandlerImpl{jobServ: jobServ}
+}
+
+func (h *JobHandlerImpl) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	jobID, err := httputils.GetURLParam[int64](r, "id")
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	res, err := h.jobServ.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	httputils.SendResponse(w, httputils.Success, res, nil, nil)
+}
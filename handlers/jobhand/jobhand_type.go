@@ -0,0 +1,7 @@
+package jobhand
+
+import "net/http"
+
+type JobHandler interface {
+	GetJob(w http.ResponseWriter, r *http.Request)
+}
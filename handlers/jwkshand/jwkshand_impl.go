@@ -0,0 +1,30 @@
+package jwkshand
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ARF-DEV/image-processing-api/utils/jwtutils"
+)
+
+type JWKSHandlerImpl struct {
+	jwks []byte
+}
+
+// New encodes the public keys of keys once, the keys don't change while the
+// server runs.
+func New(keys *jwtutils.KeySet) (JWKSHandler, error) {
+	jwks, err := json.Marshal(keys.JWKS())
+	if err != nil {
+		return nil, err
+	}
+	return &JWKSHandlerImpl{jwks: jwks}, nil
+}
+
+// GetJWKS writes the key set as is, without the response envelope, as JWKS
+// clients expect it.
+func (h *JWKSHandlerImpl) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(h.jwks)
+}
@@ -0,0 +1,7 @@
+package jwkshand
+
+import "net/http"
+
+type JWKSHandler interface {
+	GetJWKS(w http.ResponseWriter, r *http.Request)
+}
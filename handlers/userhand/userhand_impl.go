@@ -3,6 +3,7 @@ package userhand
 import (
 	"net/http"
 
+	"github.com/ARF-DEV/image-processing-api/middleware"
 	"github.com/ARF-DEV/image-processing-api/model"
 	"github.com/ARF-DEV/image-processing-api/services/userserv"
 	"github.com/ARF-DEV/image-processing-api/utils/httputils"
@@ -34,6 +35,35 @@ func (h *UserHandlerImpl) Login(w http.ResponseWriter, r *http.Request) {
 	httputils.SendResponse(w, httputils.Success, res, nil, nil)
 }
 
+func (h *UserHandlerImpl) Refresh(w http.ResponseWriter, r *http.Request) {
+	req := model.RefreshTokenRequest{}
+	if err := httputils.ParseRequestBody(r, &req); err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	res, err := h.userServ.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	httputils.SendResponse(w, httputils.Success, res, nil, nil)
+}
+
+func (h *UserHandlerImpl) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, err := middleware.GetPrincipal(r.Context())
+	if err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+	if err := h.userServ.Logout(r.Context(), principal); err != nil {
+		httputils.SendResponse(w, err.Error(), nil, nil, err)
+		return
+	}
+
+	httputils.SendResponse(w, httputils.Success, nil, nil, nil)
+}
+
 func (h *UserHandlerImpl) Register(w http.ResponseWriter, r *http.Request) {
 	req := model.LoginRegisterRequest{}
 	if err := httputils.ParseRequestBody(r, &req); err != nil {
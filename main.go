@@ -12,14 +12,30 @@ import (
 
 	"github.com/ARF-DEV/image-processing-api/configs"
 	"github.com/ARF-DEV/image-processing-api/handlers"
+	"github.com/ARF-DEV/image-processing-api/handlers/adminhand"
+	"github.com/ARF-DEV/image-processing-api/handlers/apikeyhand"
 	"github.com/ARF-DEV/image-processing-api/handlers/imagehand"
+	"github.com/ARF-DEV/image-processing-api/handlers/jobhand"
+	"github.com/ARF-DEV/image-processing-api/handlers/jwkshand"
 	"github.com/ARF-DEV/image-processing-api/handlers/userhand"
+	"github.com/ARF-DEV/image-processing-api/middleware"
 	producerconsumer "github.com/ARF-DEV/image-processing-api/producer_consumer"
+	"github.com/ARF-DEV/image-processing-api/repos/apikeyrepo"
 	"github.com/ARF-DEV/image-processing-api/repos/googlecloudstorage"
 	"github.com/ARF-DEV/image-processing-api/repos/imagerepo"
+	"github.com/ARF-DEV/image-processing-api/repos/jobrepo"
+	"github.com/ARF-DEV/image-processing-api/repos/localstorage"
+	"github.com/ARF-DEV/image-processing-api/repos/s3storage"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/repos/tokenrepo"
 	"github.com/ARF-DEV/image-processing-api/repos/userrepo"
+	"github.com/ARF-DEV/image-processing-api/services/adminserv"
+	"github.com/ARF-DEV/image-processing-api/services/apikeyserv"
 	"github.com/ARF-DEV/image-processing-api/services/imageserv"
+	"github.com/ARF-DEV/image-processing-api/services/jobserv"
 	"github.com/ARF-DEV/image-processing-api/services/userserv"
+	"github.com/ARF-DEV/image-processing-api/transform"
+	"github.com/ARF-DEV/image-processing-api/utils/jwtutils"
 )
 
 func main() {
@@ -27,19 +43,35 @@ func main() {
 		panic(err)
 	}
 	cfg := configs.GetConfig()
+	// with no key anyone could sign urls
+	if len(cfg.SignedURLKey()) == 0 {
+		panic("SIGNED_URL_KEY must be set when SECRET_KEY isn't")
+	}
 	db, err := configs.SetupDB(cfg.DB_MASTER)
 	if err != nil {
 		panic(err)
 	}
 	fmt.Println("DB connected!!")
 
+	tokenConfig, err := jwtutils.NewTokenConfig(cfg)
+	if err != nil {
+		panic(err)
+	}
+
 	userRepo := userrepo.New(db)
-	gcsRepo := googlecloudstorage.New(context.Background(), cfg)
-	defer gcsRepo.Close()
-	fmt.Println("GCS connected")
+	tokenRepo := tokenrepo.New(db)
+	apiKeyRepo := apikeyrepo.New(db)
+	storageRepo, fileHandler, err := setupStorage(context.Background(), cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer storageRepo.Close()
+	fmt.Printf("storage (%s) connected\n", cfg.STORAGE_BACKEND)
 
 	imageRepo := imagerepo.New(db)
-	consumer, err := producerconsumer.NewConsumer(cfg.RABBITMQ_URI, imageRepo, gcsRepo)
+	jobRepo := jobrepo.New(db)
+	transformer := transform.New(storageRepo, imageRepo)
+	consumer, err := producerconsumer.NewConsumer(cfg.RABBITMQ_URI, imageRepo, jobRepo, transformer)
 	if err != nil {
 		panic(err)
 	}
@@ -53,13 +85,23 @@ func main() {
 	defer producer.Close()
 
 	fmt.Println("RabbitMQ connected")
-	userServ := userserv.New(userRepo)
-	imageServ := imageserv.New(gcsRepo, imageRepo, producer)
+	userServ := userserv.New(userRepo, tokenRepo, tokenConfig)
+	imageServ := imageserv.New(storageRepo, imageRepo, jobRepo, transformer, producer)
+	jobServ := jobserv.New(jobRepo, imageRepo)
+	adminServ := adminserv.New(storageRepo, userRepo, tokenRepo, imageRepo)
+	apiKeyServ := apikeyserv.New(apiKeyRepo)
 
 	imageHand := imagehand.New(imageServ)
 	userHand := userhand.New(userServ)
+	jobHand := jobhand.New(jobServ)
+	adminHand := adminhand.New(adminServ)
+	apiKeyHand := apikeyhand.New(apiKeyServ)
+	jwksHand, err := jwkshand.New(tokenConfig.Keys)
+	if err != nil {
+		panic(err)
+	}
 
-	h := handlers.CreateHandlers(userHand, imageHand)
+	h := handlers.CreateHandlers(middleware.NewAuthenticator(userRepo, tokenRepo, apiKeyRepo, tokenConfig), userHand, imageHand, jobHand, adminHand, apiKeyHand, jwksHand, fileHandler)
 
 	server := http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.PORT),
@@ -81,3 +123,29 @@ func main() {
 	}
 
 }
+
+// setupStorage creates the storage backend selected by STORAGE_BACKEND, along
+// with the handler serving its files when the backend can't serve them itself.
+func setupStorage(ctx context.Context, cfg *configs.Config) (storagerepo.StorageRepo, http.Handler, error) {
+	switch cfg.STORAGE_BACKEND {
+	case configs.STORAGE_GCS:
+		repo, err := googlecloudstorage.New(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, nil, nil
+	case configs.STORAGE_LOCAL:
+		repo, err := localstorage.New(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo.FileHandler(), nil
+	case configs.STORAGE_S3:
+		repo, err := s3storage.New(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.STORAGE_BACKEND)
+}
@@ -1,37 +1,164 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/apikeyrepo"
+	"github.com/ARF-DEV/image-processing-api/repos/tokenrepo"
+	"github.com/ARF-DEV/image-processing-api/repos/userrepo"
+	"github.com/ARF-DEV/image-processing-api/utils/apikeyutils"
 	"github.com/ARF-DEV/image-processing-api/utils/httputils"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/spf13/viper"
+	"github.com/ARF-DEV/image-processing-api/utils/jwtutils"
 )
 
-func Authenticate(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authorization := r.Header.Get("Authorization")
-		if !strings.Contains(authorization, "Bearer ") {
-			fmt.Println(authorization)
-			httputils.SendResponse(w, httputils.ErrUnauthorized.Error(), nil, nil, httputils.ErrUnauthorized)
-			return
-		}
+// API_KEY_HEADER carries the api keys accepted by Authenticate.
+const API_KEY_HEADER string = "X-API-Key"
 
-		tokenStr := strings.TrimPrefix(authorization, "Bearer ")
-		claims := jwt.RegisteredClaims{}
-		_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return []byte(viper.GetString("SECRET_KEY")), nil
-		})
+type Authenticator struct {
+	userRepo    userrepo.UserRepo
+	tokenRepo   tokenrepo.TokenRepo
+	apiKeyRepo  apikeyrepo.APIKeyRepo
+	tokenConfig jwtutils.TokenConfig
+}
 
+func NewAuthenticator(userRepo userrepo.UserRepo, tokenRepo tokenrepo.TokenRepo, apiKeyRepo apikeyrepo.APIKeyRepo, tokenConfig jwtutils.TokenConfig) *Authenticator {
+	return &Authenticator{userRepo: userRepo, tokenRepo: tokenRepo, apiKeyRepo: apiKeyRepo, tokenConfig: tokenConfig}
+}
+
+// Authenticate accepts requests with a valid bearer access token that wasn't
+// revoked by a logout, or with an api key in the X-API-Key header, of a user
+// that isn't disabled. The caller is available through GetPrincipal.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var principal model.Principal
+		var err error
+		if key := r.Header.Get(API_KEY_HEADER); key != "" {
+			principal, err = a.authenticateAPIKey(r.Context(), key)
+		} else {
+			principal, err = a.authenticateToken(r.Context(), r.Header.Get("Authorization"))
+		}
 		if err != nil {
-			httputils.SendResponse(w, err.Error(), nil, nil, httputils.ErrUnauthorized)
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(model.ContextWithPrincipal(r.Context(), principal)))
 	})
 }
+
+func (a *Authenticator) authenticateToken(ctx context.Context, authorization string) (model.Principal, error) {
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return model.Principal{}, httputils.ErrUnauthorized
+	}
+
+	tokenStr := strings.TrimPrefix(authorization, "Bearer ")
+	claims, err := jwtutils.ParseAccessToken(a.tokenConfig, tokenStr)
+	if err != nil {
+		return model.Principal{}, err
+	}
+
+	revoked, err := a.tokenRepo.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		log.Println("error when checking token revocation: ", err)
+		return model.Principal{}, err
+	}
+	if revoked {
+		return model.Principal{}, httputils.ErrTokenRevoked
+	}
+
+	// ParseAccessToken already checked the subject
+	principal, _ := claims.Principal()
+	if _, err := a.checkUser(ctx, principal.UserID); err != nil {
+		return model.Principal{}, err
+	}
+	return principal, nil
+}
+
+// authenticateAPIKey grants the scopes of the key that the role of its user
+// still has, the role is the current one of the user.
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, key string) (model.Principal, error) {
+	prefix, err := apikeyutils.Prefix(key)
+	if err != nil {
+		return model.Principal{}, fmt.Errorf("%w: %v", httputils.ErrUnauthorized, err)
+	}
+	apiKey, err := a.apiKeyRepo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Principal{}, fmt.Errorf("%w: %v", httputils.ErrUnauthorized, apikeyutils.ErrInvalidKey)
+		}
+		return model.Principal{}, err
+	}
+	if !apikeyutils.CompareAPIKey(apiKey.KeyHash, key) {
+		return model.Principal{}, fmt.Errorf("%w: %v", httputils.ErrUnauthorized, apikeyutils.ErrInvalidKey)
+	}
+	if apiKey.RevokedAt != nil {
+		return model.Principal{}, httputils.ErrTokenRevoked
+	}
+	if apiKey.ExpiresAt != nil && !time.Now().Before(*apiKey.ExpiresAt) {
+		return model.Principal{}, fmt.Errorf("%w: api key expired", httputils.ErrUnauthorized)
+	}
+
+	user, err := a.checkUser(ctx, apiKey.UserID)
+	if err != nil {
+		return model.Principal{}, err
+	}
+	roleScopes := model.RoleScopes(user.Role)
+	var scopes []string
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(roleScopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if err := a.apiKeyRepo.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		log.Println("error when updating api key last use: ", err)
+	}
+	return model.Principal{
+		UserID:   user.ID,
+		Role:     user.Role,
+		Scopes:   scopes,
+		APIKeyID: apiKey.ID,
+	}, nil
+}
+
+// checkUser rejects tokens of users that were disabled, or deleted, after
+// the token was issued.
+func (a *Authenticator) checkUser(ctx context.Context, userID int64) (model.User, error) {
+	user, err := a.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, fmt.Errorf("%w: user not found", httputils.ErrUnauthorized)
+		}
+		return model.User{}, err
+	}
+	if user.Disabled {
+		return model.User{}, fmt.Errorf("%w: account disabled", httputils.ErrForbidden)
+	}
+	return user, nil
+}
+
+// GetUserID returns the id of the user authenticated by Authenticate.
+func GetUserID(ctx context.Context) (int64, error) {
+	principal, err := GetPrincipal(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return principal.UserID, nil
+}
+
+// GetPrincipal returns the caller authenticated by Authenticate.
+func GetPrincipal(ctx context.Context) (model.Principal, error) {
+	principal, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return model.Principal{}, httputils.ErrUnauthorized
+	}
+	return principal, nil
+}
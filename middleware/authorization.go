@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+// RequireRole only lets callers with one of roles through, it must run after
+// the middleware authenticating the caller.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := GetPrincipal(r.Context())
+			if err != nil {
+				httputils.SendResponse(w, err.Error(), nil, nil, err)
+				return
+			}
+			for _, role := range roles {
+				if principal.HasRole(role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			err = fmt.Errorf("%w: requires the %s role", httputils.ErrForbidden, strings.Join(roles, " or "))
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
+		})
+	}
+}
+
+// RequireScope only lets callers granted every one of scopes through, it
+// must run after the middleware authenticating the caller.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := GetPrincipal(r.Context())
+			if err != nil {
+				httputils.SendResponse(w, err.Error(), nil, nil, err)
+				return
+			}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					err := fmt.Errorf("%w: requires the %s scope", httputils.ErrForbidden, scope)
+					httputils.SendResponse(w, err.Error(), nil, nil, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAccessToken rejects callers authenticated with an api key, so a
+// leaked key can't be used to manage the account. It must run after the
+// middleware authenticating the caller.
+func RequireAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := GetPrincipal(r.Context())
+		if err != nil {
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
+			return
+		}
+		if principal.APIKeyID != 0 {
+			err := fmt.Errorf("%w: api keys can't be used here", httputils.ErrForbidden)
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,45 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/middleware"
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+func TestRequireRoleAndScope(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	user := model.Principal{UserID: 1, Role: model.ROLE_USER, Scopes: []string{model.SCOPE_IMAGES_READ}}
+	admin := model.Principal{UserID: 2, Role: model.ROLE_ADMIN}
+	apiKey := model.Principal{UserID: 1, Role: model.ROLE_USER, Scopes: []string{model.SCOPE_IMAGES_READ}, APIKeyID: 3}
+
+	tests := []struct {
+		name      string
+		handler   http.Handler
+		principal *model.Principal
+		expected  int
+	}{
+		{"role allowed", middleware.RequireRole(model.ROLE_ADMIN)(ok), &admin, http.StatusNoContent},
+		{"role denied", middleware.RequireRole(model.ROLE_ADMIN)(ok), &user, http.StatusForbidden},
+		{"scope allowed", middleware.RequireScope(model.SCOPE_IMAGES_READ)(ok), &user, http.StatusNoContent},
+		{"scope denied", middleware.RequireScope(model.SCOPE_IMAGES_READ, model.SCOPE_IMAGES_WRITE)(ok), &user, http.StatusForbidden},
+		{"unauthenticated", middleware.RequireScope(model.SCOPE_IMAGES_READ)(ok), nil, http.StatusUnauthorized},
+		{"access token", middleware.RequireAccessToken(ok), &user, http.StatusNoContent},
+		{"api key denied", middleware.RequireAccessToken(ok), &apiKey, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.principal != nil {
+			r = r.WithContext(model.ContextWithPrincipal(r.Context(), *test.principal))
+		}
+		w := httptest.NewRecorder()
+		test.handler.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Fatalf("%s: error expected %v, but got %v", test.name, test.expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/signutils"
+)
+
+// VerifySignature authenticates requests by a url signed with signutils.Sign
+// instead of a token, the user the url was signed for is available through
+// GetUserID with the images:read scope only. Unsigned, tampered and expired
+// urls are forbidden, as are the urls of users disabled since they were
+// signed.
+func (a *Authenticator) VerifySignature(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := signutils.Verify(configs.GetConfig().SignedURLKey(), r.URL.Path, r.URL.Query(), time.Now())
+		if err != nil {
+			err = fmt.Errorf("%w: %v", httputils.ErrForbidden, err)
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
+			return
+		}
+		if _, err := a.checkUser(r.Context(), userID); err != nil {
+			httputils.SendResponse(w, err.Error(), nil, nil, err)
+			return
+		}
+
+		principal := model.Principal{
+			UserID: userID,
+			Role:   model.ROLE_USER,
+			Scopes: []string{model.SCOPE_IMAGES_READ},
+		}
+		next.ServeHTTP(w, r.WithContext(model.ContextWithPrincipal(r.Context(), principal)))
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddOwnerIdToImages, downAddOwnerIdToImages)
+}
+
+func upAddOwnerIdToImages(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	// owner_id is nullable because images uploaded before ownership existed have no owner.
+	sq := `ALTER TABLE images
+		ADD COLUMN owner_id INTEGER REFERENCES users(id) ON DELETE CASCADE`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `CREATE INDEX images_owner_id_idx ON images (owner_id)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("images owner_id up")
+	return nil
+}
+
+func downAddOwnerIdToImages(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `ALTER TABLE images DROP COLUMN owner_id`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateTransformJobsTable, downCreateTransformJobsTable)
+}
+
+func upCreateTransformJobsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	sq := `CREATE TABLE transform_jobs (
+		id SERIAL PRIMARY KEY,
+		image_id INTEGER NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+		owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(16) NOT NULL DEFAULT 'queued',
+		error TEXT NOT NULL DEFAULT '',
+		result_image_id INTEGER REFERENCES images(id) ON DELETE SET NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `CREATE INDEX transform_jobs_owner_id_idx ON transform_jobs (owner_id)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("transform_jobs up")
+	return nil
+}
+
+func downCreateTransformJobsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `DROP TABLE transform_jobs`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
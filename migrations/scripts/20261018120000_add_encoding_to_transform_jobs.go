@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEncodingToTransformJobs, downAddEncodingToTransformJobs)
+}
+
+func upAddEncodingToTransformJobs(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	sq := `ALTER TABLE transform_jobs ADD COLUMN encoding JSONB`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("transform_jobs encoding up")
+	return nil
+}
+
+func downAddEncodingToTransformJobs(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `ALTER TABLE transform_jobs DROP COLUMN encoding`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
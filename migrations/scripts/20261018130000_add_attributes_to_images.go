@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddAttributesToImages, downAddAttributesToImages)
+}
+
+func upAddAttributesToImages(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	// the columns are nullable, images stored before them are not described.
+	sq := `ALTER TABLE images
+		ADD COLUMN width INTEGER,
+		ADD COLUMN height INTEGER,
+		ADD COLUMN format VARCHAR(16),
+		ADD COLUMN byte_size BIGINT,
+		ADD COLUMN color_model VARCHAR(32),
+		ADD COLUMN has_alpha BOOLEAN,
+		ADD COLUMN content_hash VARCHAR(64),
+		ADD COLUMN exif JSONB`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("images attributes up")
+	return nil
+}
+
+func downAddAttributesToImages(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `ALTER TABLE images
+		DROP COLUMN width,
+		DROP COLUMN height,
+		DROP COLUMN format,
+		DROP COLUMN byte_size,
+		DROP COLUMN color_model,
+		DROP COLUMN has_alpha,
+		DROP COLUMN content_hash,
+		DROP COLUMN exif`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
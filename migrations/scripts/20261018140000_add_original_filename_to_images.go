@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddOriginalFilenameToImages, downAddOriginalFilenameToImages)
+}
+
+func upAddOriginalFilenameToImages(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	sq := `ALTER TABLE images ADD COLUMN original_filename VARCHAR(255)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("images original_filename up")
+	return nil
+}
+
+func downAddOriginalFilenameToImages(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `ALTER TABLE images DROP COLUMN original_filename`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddImageHashIndexes, downAddImageHashIndexes)
+}
+
+func upAddImageHashIndexes(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	// transform_hash identifies the source and the transformations a result
+	// was made with, uploads have none.
+	sq := `ALTER TABLE images ADD COLUMN transform_hash VARCHAR(64)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `CREATE INDEX images_owner_id_content_hash_idx ON images (owner_id, content_hash)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `CREATE INDEX images_owner_id_transform_hash_idx ON images (owner_id, transform_hash)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("images hash indexes up")
+	return nil
+}
+
+func downAddImageHashIndexes(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `DROP INDEX images_owner_id_transform_hash_idx`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `DROP INDEX images_owner_id_content_hash_idx`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `ALTER TABLE images DROP COLUMN transform_hash`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
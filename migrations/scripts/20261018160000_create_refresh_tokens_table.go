@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateRefreshTokensTable, downCreateRefreshTokensTable)
+}
+
+func upCreateRefreshTokensTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	// only the sha256 of refresh tokens is stored, tokens rotated from the
+	// same login share a session_id
+	sq := `CREATE TABLE refresh_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		session_id VARCHAR(36) NOT NULL,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	// access tokens revoked before they expire, by jti
+	sq = `CREATE TABLE revoked_tokens (
+		jti VARCHAR(36) PRIMARY KEY,
+		expires_at TIMESTAMP NOT NULL
+	)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("refresh_tokens up")
+	return nil
+}
+
+func downCreateRefreshTokensTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `DROP TABLE revoked_tokens`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `DROP TABLE refresh_tokens`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
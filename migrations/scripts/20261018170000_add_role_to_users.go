@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddRoleToUsers, downAddRoleToUsers)
+}
+
+func upAddRoleToUsers(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	sq := `ALTER TABLE users
+		ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user',
+		ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT false`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	fmt.Println("users role up")
+	return nil
+}
+
+func downAddRoleToUsers(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `ALTER TABLE users DROP COLUMN role, DROP COLUMN disabled`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
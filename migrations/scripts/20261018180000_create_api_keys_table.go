@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateAPIKeysTable, downCreateAPIKeysTable)
+}
+
+func upCreateAPIKeysTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	// keys are looked up by their prefix and checked against the bcrypt hash
+	sq := `CREATE TABLE api_keys (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL,
+		prefix VARCHAR(16) NOT NULL UNIQUE,
+		key_hash VARCHAR(255) NOT NULL,
+		scopes JSONB NOT NULL,
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	sq = `CREATE INDEX api_keys_user_id_idx ON api_keys (user_id)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+
+	fmt.Println("api_keys up")
+	return nil
+}
+
+func downCreateAPIKeysTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `DROP TABLE api_keys`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
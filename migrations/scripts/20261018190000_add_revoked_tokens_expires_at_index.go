@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddRevokedTokensExpiresAtIndex, downAddRevokedTokensExpiresAtIndex)
+}
+
+func upAddRevokedTokensExpiresAtIndex(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	// expired revoked tokens are deleted on every logout
+	sq := `CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at)`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	fmt.Println("revoked_tokens expires_at index up")
+	return nil
+}
+
+func downAddRevokedTokensExpiresAtIndex(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	sq := `DROP INDEX revoked_tokens_expires_at_idx`
+	if _, err := tx.ExecContext(ctx, sq); err != nil {
+		return err
+	}
+	return nil
+}
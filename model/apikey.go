@@ -0,0 +1,124 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+)
+
+// APIKey is a stored api key, only the prefix and a hash of the key itself
+// are kept. Requests made with it get its scopes instead of the ones of the
+// role of its user.
+type APIKey struct {
+	ID         int64      `db:"id"`
+	UserID     int64      `db:"user_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     Scopes     `db:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+func (k APIKey) ToAPIKeyResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+type APIKeys []APIKey
+
+func (k APIKeys) ToAPIKeyResponses() APIKeyResponses {
+	var responses APIKeyResponses
+	for _, key := range k {
+		responses = append(responses, key.ToAPIKeyResponse())
+	}
+	return responses
+}
+
+type Scopes []string
+
+func (s Scopes) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *Scopes) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("can't scan %T into Scopes", src)
+}
+
+// CreateAPIKeyRequest names the key and picks its scopes, which must be
+// granted to the role of the user. ExpiresIn is in seconds, keys without it
+// don't expire.
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int64    `json:"expires_in"`
+}
+
+func (r CreateAPIKeyRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 255 {
+		return fmt.Errorf("name can't be longer than 255 characters")
+	}
+	if len(r.Scopes) == 0 {
+		return fmt.Errorf("scopes is required")
+	}
+	for _, scope := range r.Scopes {
+		if !IsScope(scope) {
+			return fmt.Errorf("scopes must be one of %s", strings.Join(scopes, ", "))
+		}
+	}
+	if r.ExpiresIn < 0 {
+		return fmt.Errorf("expires_in can't be negative")
+	}
+	// checked in seconds, larger values would overflow a time.Duration
+	if maxExpiresIn := int64(configs.GetConfig().API_KEY_MAX_TTL / time.Second); r.ExpiresIn > maxExpiresIn {
+		return fmt.Errorf("expires_in can't be longer than %d seconds", maxExpiresIn)
+	}
+	return nil
+}
+
+type APIKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type APIKeyResponses []APIKeyResponse
+
+// CreatedAPIKeyResponse is the only response carrying the key, it can't be
+// retrieved later.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
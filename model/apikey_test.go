@@ -0,0 +1,24 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+func TestCreateAPIKeyRequestValidate(t *testing.T) {
+	req := model.CreateAPIKeyRequest{Name: "ci", Scopes: []string{model.SCOPE_IMAGES_READ}, ExpiresIn: 3600}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// would wrap around once turned into a time.Duration
+	req.ExpiresIn = 10_000_000_000
+	if err := req.Validate(); err == nil {
+		t.Fatal("error expected expires_in to be too long")
+	}
+	req.ExpiresIn = -1
+	if err := req.Validate(); err == nil {
+		t.Fatal("error expected expires_in to be negative")
+	}
+}
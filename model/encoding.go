@@ -0,0 +1,72 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	COMPRESSION_DEFAULT string = "default"
+	COMPRESSION_NONE    string = "none"
+	COMPRESSION_SPEED   string = "speed"
+	COMPRESSION_BEST    string = "best"
+)
+
+var compressionLevels = []string{COMPRESSION_DEFAULT, COMPRESSION_NONE, COMPRESSION_SPEED, COMPRESSION_BEST}
+
+// CompressTransformRequest controls how the result is encoded. Quality
+// applies to JPEG and CompressionLevel to PNG. With TargetSize the highest
+// JPEG quality (or strongest PNG compression) producing at most that many
+// bytes is picked.
+type CompressTransformRequest struct {
+	Quality          int64  `json:"quality"`
+	CompressionLevel string `json:"compression_level"`
+	TargetSize       int64  `json:"target_size"`
+}
+
+func (r CompressTransformRequest) Validate() error {
+	if r.Quality < 0 || r.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	if r.CompressionLevel != "" && !slices.Contains(compressionLevels, r.CompressionLevel) {
+		return fmt.Errorf("compression_level must be one of %s", strings.Join(compressionLevels, ", "))
+	}
+	if r.TargetSize < 0 {
+		return fmt.Errorf("target_size can't be negative")
+	}
+	return nil
+}
+
+// EncodingResult records the settings an image was encoded with.
+type EncodingResult struct {
+	Format           string `json:"format"`
+	Quality          int64  `json:"quality,omitempty"`
+	CompressionLevel string `json:"compression_level,omitempty"`
+	Size             int64  `json:"size"`
+	// TargetMet is set when a target size was requested.
+	TargetMet *bool `json:"target_met,omitempty"`
+	// Reused is set when an existing result was returned instead of
+	// encoding a new one, only Format and Size are known then.
+	Reused bool `json:"reused,omitempty"`
+}
+
+func (e EncodingResult) Value() (driver.Value, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (e *EncodingResult) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("can't scan %T into EncodingResult", src)
+}
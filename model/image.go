@@ -1,13 +1,17 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"image"
 	"io"
+	"slices"
 	"strings"
-	"time"
+	"unicode/utf8"
 
 	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/utils/colorutils"
 )
 
 type UploadImageRequest struct {
@@ -16,25 +20,143 @@ type UploadImageRequest struct {
 }
 
 type Image struct {
-	ID  int64  `db:"id"`
-	URL string `db:"url"`
+	ID      int64  `db:"id"`
+	URL     string `db:"url"`
+	OwnerID int64  `db:"owner_id"`
+	// OriginalFilename is the name the file was uploaded with, it is not
+	// used to store the file.
+	OriginalFilename string `db:"original_filename"`
+	// TransformHash is set on transformation results, see
+	// transform.Transformer.Hash.
+	TransformHash string `db:"transform_hash"`
+	ImageAttributes
+}
+
+const maxFilenameLength int = 255
+
+// CleanFilename keeps the last element of a client supplied file name, with
+// either slash as separator, cut to the length of the column.
+func CleanFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// ImageAttributes describe the stored file of an image.
+type ImageAttributes struct {
+	Width      int64  `db:"width"`
+	Height     int64  `db:"height"`
+	Format     string `db:"format"`
+	ByteSize   int64  `db:"byte_size"`
+	ColorModel string `db:"color_model"`
+	HasAlpha   bool   `db:"has_alpha"`
+	// ContentHash is the hex encoded sha256 of the file.
+	ContentHash string `db:"content_hash"`
+	EXIF        EXIF   `db:"exif"`
+}
+
+// EXIF maps tag names to their parsed values.
+type EXIF map[string]any
+
+// exifSummaryTags are the EXIF fields included in image responses, the
+// metadata endpoint returns all of them.
+var exifSummaryTags = []string{"Make", "Model", "DateTimeOriginal", "Orientation", "ExposureTime", "FNumber", "ISOSpeedRatings", "FocalLength"}
+
+func (e EXIF) Summary() EXIF {
+	summary := EXIF{}
+	for _, tag := range exifSummaryTags {
+		if value, found := e[tag]; found {
+			summary[tag] = value
+		}
+	}
+	if len(summary) == 0 {
+		return nil
+	}
+	return summary
+}
+
+func (e EXIF) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (e *EXIF) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("can't scan %T into EXIF", src)
 }
 
 func (i Image) ToImageResponse(cfg *configs.Config) ImageResponse {
 	image := ImageResponse{
-		ID:  i.ID,
-		URL: i.URL,
+		ID:               i.ID,
+		URL:              i.URL,
+		OwnerID:          i.OwnerID,
+		OriginalFilename: i.OriginalFilename,
+		Width:            i.Width,
+		Height:           i.Height,
+		Format:           i.Format,
+		ByteSize:         i.ByteSize,
+		ColorModel:       i.ColorModel,
+		HasAlpha:         i.HasAlpha,
+		ContentHash:      i.ContentHash,
+		EXIF:             i.EXIF.Summary(),
 	}
 
 	if image.URL != "" {
-		image.URL = fmt.Sprintf("%s%s", cfg.GOOGLE_STORAGE_URL, image.URL)
+		image.URL = fmt.Sprintf("%s%s", cfg.StorageURL(), image.URL)
 	}
 	return image
 }
 
 type ImageResponse struct {
-	ID  int64  `json:"id"`
-	URL string `json:"url"`
+	ID               int64  `json:"id"`
+	URL              string `json:"url"`
+	OwnerID          int64  `json:"owner_id"`
+	OriginalFilename string `json:"original_filename"`
+	Width            int64  `json:"width"`
+	Height           int64  `json:"height"`
+	Format           string `json:"format"`
+	ByteSize         int64  `json:"byte_size"`
+	ColorModel       string `json:"color_model"`
+	HasAlpha         bool   `json:"has_alpha"`
+	ContentHash      string `json:"content_hash"`
+	EXIF             EXIF   `json:"exif,omitempty"`
+}
+
+// ImageMetadataResponse is an image with its full EXIF.
+type ImageMetadataResponse struct {
+	ImageResponse
+	EXIF EXIF `json:"exif"`
+}
+
+func (i Image) ToImageMetadataResponse(cfg *configs.Config) ImageMetadataResponse {
+	exif := i.EXIF
+	if exif == nil {
+		exif = EXIF{}
+	}
+	return ImageMetadataResponse{
+		ImageResponse: i.ToImageResponse(cfg),
+		EXIF:          exif,
+	}
 }
 
 type ImageResponses []ImageResponse
@@ -52,72 +174,450 @@ func (i Images) ToImageResponses(cfg *configs.Config) ImageResponses {
 }
 
 type ImageTransformRequestOpts struct {
-	ResizeTransform ResizeTransformRequest `json:"resize"`
-	CropTransform   CropTransformRequest   `json:"crop"`
-	Rotate          float64                `json:"rotate"`
-	Format          string                 `json:"format"`
-	Filters         FilterTransformRequest `json:"filters"`
+	// Pipeline lists the steps to run in order, it can't be combined with
+	// the fixed order fields below.
+	Pipeline        []TransformStep           `json:"steps"`
+	ResizeTransform ResizeTransformRequest    `json:"resize"`
+	CropTransform   CropTransformRequest      `json:"crop"`
+	Flip            string                    `json:"flip"`
+	Rotate          float64                   `json:"rotate"`
+	Format          string                    `json:"format"`
+	Filters         FilterTransformRequest    `json:"filters"`
+	Watermark       WatermarkTransformRequest `json:"watermark"`
+	Compress        CompressTransformRequest  `json:"compress"`
+	AutoOrient      bool                      `json:"auto_orient"`
+	Metadata        string                    `json:"metadata"`
 }
 
 type ImageTranformRequest struct {
 	Transform ImageTransformRequestOpts `json:"transformations"`
 }
 
-func (i *ImageTransformRequestOpts) GenerateStr() string {
+const (
+	OP_CROP        string = "crop"
+	OP_FORMAT      string = "format"
+	OP_GRAYSCALE   string = "grayscale"
+	OP_SEPIA       string = "sepia"
+	OP_RESIZE      string = "resize"
+	OP_ROTATE      string = "rotate"
+	OP_WATERMARK   string = "watermark"
+	OP_FLIP        string = "flip"
+	OP_TRANSPOSE   string = "transpose"
+	OP_TRANSVERSE  string = "transverse"
+	OP_COMPRESS    string = "compress"
+	OP_FRAME       string = "frame"
+	OP_AUTO_ORIENT string = "auto_orient"
+	OP_METADATA    string = "metadata"
+)
 
-	s := []string{}
-	if i.ResizeTransform != (ResizeTransformRequest{}) {
-		s = append(s, "resized")
+// TransformStep is a single named operation of a transformation pipeline,
+// its params are decoded by the operation registered under Op.
+type TransformStep struct {
+	Op     string          `json:"op"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+func NewTransformStep(op string, params any) TransformStep {
+	step := TransformStep{Op: op}
+	if params != nil {
+		step.Params, _ = json.Marshal(params)
 	}
-	if i.CropTransform != (CropTransformRequest{}) {
-		s = append(s, "cropped")
+	return step
+}
+
+// Steps returns the requested transformations in the order they are applied.
+// Requests without a pipeline run the fixed order fields as auto_orient,
+// crop, format, filters, resize, flip, rotate, watermark, compress and
+// metadata.
+func (i *ImageTransformRequestOpts) Steps() []TransformStep {
+	if len(i.Pipeline) > 0 {
+		return i.Pipeline
 	}
-	if i.Rotate > 0 {
-		s = append(s, "rotated")
+
+	steps := []TransformStep{}
+	if i.AutoOrient {
+		steps = append(steps, NewTransformStep(OP_AUTO_ORIENT, nil))
+	}
+	if i.CropTransform != (CropTransformRequest{}) {
+		steps = append(steps, NewTransformStep(OP_CROP, i.CropTransform))
 	}
 	if i.Format != "" {
-		s = append(s, "formated")
+		steps = append(steps, NewTransformStep(OP_FORMAT, FormatTransformRequest{Format: i.Format}))
+	}
+	if i.Filters.Grayscale {
+		steps = append(steps, NewTransformStep(OP_GRAYSCALE, nil))
+	}
+	if i.Filters.Sepia {
+		steps = append(steps, NewTransformStep(OP_SEPIA, nil))
+	}
+	if i.ResizeTransform != (ResizeTransformRequest{}) {
+		steps = append(steps, NewTransformStep(OP_RESIZE, i.ResizeTransform))
+	}
+	if i.Flip != "" {
+		steps = append(steps, NewTransformStep(OP_FLIP, FlipTransformRequest{Direction: i.Flip}))
 	}
-	if i.Filters != (FilterTransformRequest{}) {
-		s = append(s, "filtered")
+	if i.Rotate != 0 {
+		steps = append(steps, NewTransformStep(OP_ROTATE, RotateTransformRequest{Angle: i.Rotate}))
 	}
+	if i.Watermark != (WatermarkTransformRequest{}) {
+		steps = append(steps, NewTransformStep(OP_WATERMARK, i.Watermark))
+	}
+	if i.Compress != (CompressTransformRequest{}) {
+		steps = append(steps, NewTransformStep(OP_COMPRESS, i.Compress))
+	}
+	if i.Metadata != "" {
+		steps = append(steps, NewTransformStep(OP_METADATA, MetadataTransformRequest{Mode: i.Metadata}))
+	}
+	return steps
+}
 
-	s = append(s, fmt.Sprintf("%d", time.Now().Unix()))
+var stepLabels = map[string]string{
+	OP_CROP:        "cropped",
+	OP_FORMAT:      "formated",
+	OP_GRAYSCALE:   "filtered",
+	OP_SEPIA:       "filtered",
+	OP_RESIZE:      "resized",
+	OP_ROTATE:      "rotated",
+	OP_WATERMARK:   "watermarked",
+	OP_FLIP:        "flipped",
+	OP_TRANSPOSE:   "transposed",
+	OP_TRANSVERSE:  "transposed",
+	OP_COMPRESS:    "compressed",
+	OP_FRAME:       "frame",
+	OP_AUTO_ORIENT: "oriented",
+}
+
+func (i *ImageTransformRequestOpts) GenerateStr() string {
+
+	s := []string{}
+	for _, step := range i.Steps() {
+		label, found := stepLabels[step.Op]
+		if !found {
+			label = step.Op
+		}
+		if len(s) > 0 && s[len(s)-1] == label {
+			continue
+		}
+		s = append(s, label)
+	}
 
 	return strings.Join(s, "-")
 }
 
+// HasLegacyOpts reports whether any of the fixed order fields is set.
+func (i *ImageTransformRequestOpts) HasLegacyOpts() bool {
+	return i.ResizeTransform != (ResizeTransformRequest{}) ||
+		i.CropTransform != (CropTransformRequest{}) ||
+		i.Flip != "" ||
+		i.Rotate != 0 ||
+		i.Format != "" ||
+		i.Filters != (FilterTransformRequest{}) ||
+		i.Watermark != (WatermarkTransformRequest{}) ||
+		i.Compress != (CompressTransformRequest{}) ||
+		i.AutoOrient ||
+		i.Metadata != ""
+}
+
+const (
+	FILTER_NEAREST  string = "nearest"
+	FILTER_BILINEAR string = "bilinear"
+	FILTER_BICUBIC  string = "bicubic"
+	FILTER_LANCZOS  string = "lanczos"
+)
+
+var resizeFilters = []string{FILTER_NEAREST, FILTER_BILINEAR, FILTER_BICUBIC, FILTER_LANCZOS}
+
+const (
+	RESIZE_STRETCH string = "stretch"
+	RESIZE_FIT     string = "fit"
+	RESIZE_FILL    string = "fill"
+	RESIZE_COVER   string = "cover"
+	RESIZE_PAD     string = "pad"
+)
+
+var resizeModes = []string{RESIZE_STRETCH, RESIZE_FIT, RESIZE_FILL, RESIZE_COVER, RESIZE_PAD}
+
+// CheckImageSize rejects images larger than the ones accepted on upload, a
+// zero side isn't checked.
+func CheckImageSize(width int64, height int64) error {
+	cfg := configs.GetConfig()
+	if width > cfg.UPLOAD_MAX_WIDTH || height > cfg.UPLOAD_MAX_HEIGHT {
+		return fmt.Errorf("images can't be larger than %dx%d pixels", cfg.UPLOAD_MAX_WIDTH, cfg.UPLOAD_MAX_HEIGHT)
+	}
+	if width*height > cfg.UPLOAD_MAX_PIXELS {
+		return fmt.Errorf("images can't have more than %d pixels", cfg.UPLOAD_MAX_PIXELS)
+	}
+	return nil
+}
+
+// ResizeTransformRequest scales the image to Width x Height. When only one
+// dimension is given the other follows the aspect ratio. Mode decides how
+// the aspect ratio is kept when both are given:
+//   - stretch (default) ignores it,
+//   - fit scales the image to fit inside the box,
+//   - fill (or cover) scales the image to cover the box and crops the
+//     overflow around Gravity,
+//   - pad fits the image and fills the rest of the box with Background,
+//     placing the image at Gravity.
+//
+// Filter selects the resampling kernel, when empty lanczos is used to
+// downscale and bicubic to upscale.
 type ResizeTransformRequest struct {
-	Width  int64 `json:"width"`
-	Height int64 `json:"height"`
+	Width      int64  `json:"width"`
+	Height     int64  `json:"height"`
+	Mode       string `json:"mode"`
+	Gravity    string `json:"gravity"`
+	Background string `json:"background"`
+	Filter     string `json:"filter"`
 }
+
+func (r ResizeTransformRequest) Validate() error {
+	if r.Width < 0 || r.Height < 0 {
+		return fmt.Errorf("width and height can't be negative")
+	}
+	if r.Width == 0 && r.Height == 0 {
+		return fmt.Errorf("width or height must be positive")
+	}
+	if err := CheckImageSize(r.Width, r.Height); err != nil {
+		return err
+	}
+	if r.Mode != "" && !slices.Contains(resizeModes, r.Mode) {
+		return fmt.Errorf("mode must be one of %s", strings.Join(resizeModes, ", "))
+	}
+	if r.Gravity != "" && !slices.Contains(positions, r.Gravity) {
+		return fmt.Errorf("gravity must be one of %s", strings.Join(positions, ", "))
+	}
+	if r.Background != "" {
+		if _, err := colorutils.ParseColor(r.Background); err != nil {
+			return err
+		}
+	}
+	if r.Filter != "" && !slices.Contains(resizeFilters, r.Filter) {
+		return fmt.Errorf("filter must be one of %s", strings.Join(resizeFilters, ", "))
+	}
+	return nil
+}
+
 type CropTransformRequest struct {
 	X      int64 `json:"x"`
 	Y      int64 `json:"y"`
 	Width  int64 `json:"width"`
 	Height int64 `json:"height"`
 }
+
+func (r CropTransformRequest) Validate() error {
+	if r.X < 0 || r.Y < 0 {
+		return fmt.Errorf("x and y can't be negative")
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return fmt.Errorf("width and height must be positive")
+	}
+	// images can't be larger, this also keeps x+width and y+height from
+	// overflowing
+	cfg := configs.GetConfig()
+	if r.X >= cfg.UPLOAD_MAX_WIDTH || r.Y >= cfg.UPLOAD_MAX_HEIGHT {
+		return fmt.Errorf("x and y must be smaller than %d and %d", cfg.UPLOAD_MAX_WIDTH, cfg.UPLOAD_MAX_HEIGHT)
+	}
+	if r.Width > cfg.UPLOAD_MAX_WIDTH || r.Height > cfg.UPLOAD_MAX_HEIGHT {
+		return fmt.Errorf("width and height can't be larger than %d and %d", cfg.UPLOAD_MAX_WIDTH, cfg.UPLOAD_MAX_HEIGHT)
+	}
+	return nil
+}
+
 type FilterTransformRequest struct {
 	Grayscale bool `json:"grayscale"`
 	Sepia     bool `json:"sepia"`
 }
 
+// RotateTransformRequest rotates counter-clockwise. Multiples of 90 degrees
+// move pixels without resampling, other angles fill the uncovered corners
+// with Background (black by default).
+type RotateTransformRequest struct {
+	Angle      float64 `json:"angle"`
+	Background string  `json:"background"`
+}
+
+func (r RotateTransformRequest) Validate() error {
+	if r.Background != "" {
+		if _, err := colorutils.ParseColor(r.Background); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	FLIP_HORIZONTAL string = "horizontal"
+	FLIP_VERTICAL   string = "vertical"
+)
+
+// FlipTransformRequest mirrors the image, horizontal swaps left and right,
+// vertical swaps top and bottom.
+type FlipTransformRequest struct {
+	Direction string `json:"direction"`
+}
+
+func (r FlipTransformRequest) Validate() error {
+	if r.Direction != FLIP_HORIZONTAL && r.Direction != FLIP_VERTICAL {
+		return fmt.Errorf("direction must be %s or %s", FLIP_HORIZONTAL, FLIP_VERTICAL)
+	}
+	return nil
+}
+
+type FormatTransformRequest struct {
+	Format string `json:"format"`
+}
+
+func (r FormatTransformRequest) Validate() error {
+	if r.Format == "" {
+		return fmt.Errorf("format is required")
+	}
+	return nil
+}
+
+// GetBucket returns the bucket of a "/<bucket>/<object>" url.
 func (i *Image) GetBucket() string {
-	strSplits := strings.Split(i.URL, "/")
-	return strSplits[1]
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(i.URL, "/"), "/")
+	return bucket
 }
 
+// GetObject returns the object name of a "/<bucket>/<object>" url, the
+// object name can contain slashes.
 func (i *Image) GetObject() string {
-	strSplits := strings.Split(i.URL, "/")
-	return strSplits[2]
+	_, object, _ := strings.Cut(strings.TrimPrefix(i.URL, "/"), "/")
+	return object
 }
 
 type ImageInfo struct {
-	Image  image.Image
-	Format string
+	// Image is the still image, or the first frame of an animation.
+	Image     image.Image
+	Format    string
+	Encoding  CompressTransformRequest
+	Animation *Animation
+	// Metadata is read from JPEG sources, it is only written back when
+	// KeepMetadata is set.
+	Metadata     *ImageMetadata
+	KeepMetadata bool
+}
+
+// ImageMetadata holds the metadata segments of a JPEG.
+type ImageMetadata struct {
+	// Segments are the raw EXIF, XMP and ICC segments in file order.
+	Segments []MetadataSegment
+	// Orientation is the EXIF orientation, 1 when unknown.
+	Orientation int
+}
+
+type MetadataSegment struct {
+	Marker  byte
+	Payload []byte
+}
+
+const (
+	METADATA_KEEP  string = "keep"
+	METADATA_STRIP string = "strip"
+)
+
+// MetadataTransformRequest decides whether the EXIF, ICC and XMP metadata of
+// the source is written to the result, results are stripped by default.
+type MetadataTransformRequest struct {
+	Mode string `json:"mode"`
+}
+
+func (r MetadataTransformRequest) Validate() error {
+	if r.Mode != METADATA_KEEP && r.Mode != METADATA_STRIP {
+		return fmt.Errorf("mode must be %s or %s", METADATA_KEEP, METADATA_STRIP)
+	}
+	return nil
+}
+
+// Animation holds the frames of a multi-frame GIF. Frames are full canvases
+// with every previous frame already composited, so they can be transformed
+// independently.
+type Animation struct {
+	Frames []image.Image
+	// Delays are in 100ths of a second, one per frame.
+	Delays    []int
+	Disposals []byte
+	LoopCount int
+}
+
+// FrameTransformRequest extracts a single frame of an animation as a still
+// image.
+type FrameTransformRequest struct {
+	Index int64 `json:"index"`
+}
+
+func (r FrameTransformRequest) Validate() error {
+	if r.Index < 0 {
+		return fmt.Errorf("index can't be negative")
+	}
+	return nil
 }
 
 type ImageTransformBrokerRequest struct {
 	Req     ImageTransformRequestOpts `json:"opts"`
 	ImageID int64                     `json:"image_id"`
+	OwnerID int64                     `json:"owner_id"`
+	JobID   int64                     `json:"job_id"`
+}
+
+const (
+	POSITION_TOP_LEFT     string = "top-left"
+	POSITION_TOP          string = "top"
+	POSITION_TOP_RIGHT    string = "top-right"
+	POSITION_LEFT         string = "left"
+	POSITION_CENTER       string = "center"
+	POSITION_RIGHT        string = "right"
+	POSITION_BOTTOM_LEFT  string = "bottom-left"
+	POSITION_BOTTOM       string = "bottom"
+	POSITION_BOTTOM_RIGHT string = "bottom-right"
+)
+
+var positions = []string{
+	POSITION_TOP_LEFT, POSITION_TOP, POSITION_TOP_RIGHT,
+	POSITION_LEFT, POSITION_CENTER, POSITION_RIGHT,
+	POSITION_BOTTOM_LEFT, POSITION_BOTTOM, POSITION_BOTTOM_RIGHT,
+}
+
+// WatermarkTransformRequest overlays either another image of the same owner
+// or a text. Zero values fall back to a bottom-right, half transparent mark.
+type WatermarkTransformRequest struct {
+	ImageID  int64   `json:"image_id"`
+	Width    int64   `json:"width"`
+	Text     string  `json:"text"`
+	Size     float64 `json:"size"`
+	Color    string  `json:"color"`
+	Position string  `json:"position"`
+	Margin   int64   `json:"margin"`
+	Opacity  float64 `json:"opacity"`
+	Tile     bool    `json:"tile"`
+}
+
+func (r WatermarkTransformRequest) Validate() error {
+	if (r.ImageID == 0) == (r.Text == "") {
+		return fmt.Errorf("exactly one of image_id and text is required")
+	}
+	if r.Position != "" && !slices.Contains(positions, r.Position) {
+		return fmt.Errorf("position must be one of %s", strings.Join(positions, ", "))
+	}
+	if r.Color != "" {
+		if _, err := colorutils.ParseColor(r.Color); err != nil {
+			return err
+		}
+	}
+	if r.Margin < 0 || r.Width < 0 || r.Size < 0 {
+		return fmt.Errorf("margin, width and size can't be negative")
+	}
+	if err := CheckImageSize(r.Width, 0); err != nil {
+		return err
+	}
+	// the size is the height of the text in pixels
+	if maxSize := configs.GetConfig().UPLOAD_MAX_HEIGHT; r.Size > float64(maxSize) {
+		return fmt.Errorf("size can't be larger than %d", maxSize)
+	}
+	if r.Opacity < 0 || r.Opacity > 1 {
+		return fmt.Errorf("opacity must be between 0 and 1")
+	}
+	return nil
 }
@@ -0,0 +1,37 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+func TestImageObject(t *testing.T) {
+	tests := map[string][2]string{
+		"/images/749574.jpg":                 {"images", "749574.jpg"},
+		"/images/users/1/a.b.c.jpg":          {"images", "users/1/a.b.c.jpg"},
+		"/bucket/users/12/0f3c-9a1d-4e.tiff": {"bucket", "users/12/0f3c-9a1d-4e.tiff"},
+	}
+	for url, expected := range tests {
+		img := model.Image{URL: url}
+		if img.GetBucket() != expected[0] || img.GetObject() != expected[1] {
+			t.Fatalf("error expected %v, but got [%v %v]", expected, img.GetBucket(), img.GetObject())
+		}
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":                     "photo.jpg",
+		"../../etc/passwd":              "passwd",
+		`C:\Users\me\Pictures\cat.png`:  "cat.png",
+		"dir/":                          "",
+		strings.Repeat("é", 200) + ".x": strings.Repeat("é", 127),
+	}
+	for name, expected := range tests {
+		if got := model.CleanFilename(name); got != expected {
+			t.Fatalf("error expected %q, but got %q", expected, got)
+		}
+	}
+}
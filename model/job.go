@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+const (
+	JOB_QUEUED     string = "queued"
+	JOB_PROCESSING string = "processing"
+	JOB_SUCCEEDED  string = "succeeded"
+	JOB_FAILED     string = "failed"
+)
+
+type TransformJob struct {
+	ID            int64           `db:"id"`
+	ImageID       int64           `db:"image_id"`
+	OwnerID       int64           `db:"owner_id"`
+	Status        string          `db:"status"`
+	Error         string          `db:"error"`
+	ResultImageID int64           `db:"result_image_id"`
+	Encoding      *EncodingResult `db:"encoding"`
+	CreatedAt     time.Time       `db:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at"`
+}
+
+func (j TransformJob) ToTransformJobResponse() TransformJobResponse {
+	return TransformJobResponse{
+		ID:        j.ID,
+		ImageID:   j.ImageID,
+		Status:    j.Status,
+		Error:     j.Error,
+		Encoding:  j.Encoding,
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+}
+
+type TransformJobResponse struct {
+	ID        int64           `json:"id"`
+	ImageID   int64           `json:"image_id"`
+	Status    string          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	Result    *ImageResponse  `json:"result"`
+	Encoding  *EncodingResult `json:"encoding,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+const (
+	ROLE_USER  string = "user"
+	ROLE_ADMIN string = "admin"
+)
+
+var roles = []string{ROLE_USER, ROLE_ADMIN}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+const (
+	SCOPE_IMAGES_READ      string = "images:read"
+	SCOPE_IMAGES_WRITE     string = "images:write"
+	SCOPE_IMAGES_TRANSFORM string = "images:transform"
+)
+
+var scopes = []string{SCOPE_IMAGES_READ, SCOPE_IMAGES_WRITE, SCOPE_IMAGES_TRANSFORM}
+
+// IsScope reports whether scope is one of the known scopes.
+func IsScope(scope string) bool {
+	return slices.Contains(scopes, scope)
+}
+
+// roleScopes are the scopes of each role. Admins work with their own images
+// like any user, the admin endpoints check the role instead of a scope.
+var roleScopes = map[string][]string{
+	ROLE_USER:  {SCOPE_IMAGES_READ, SCOPE_IMAGES_WRITE, SCOPE_IMAGES_TRANSFORM},
+	ROLE_ADMIN: {SCOPE_IMAGES_READ, SCOPE_IMAGES_WRITE, SCOPE_IMAGES_TRANSFORM},
+}
+
+// RoleScopes returns the scopes granted to tokens of users with role, none
+// for unknown roles.
+func RoleScopes(role string) []string {
+	return slices.Clone(roleScopes[role])
+}
+
+// Principal is the authenticated caller of a request. TokenID, SessionID and
+// ExpiresAt describe the access token it was authenticated with, APIKeyID is
+// set instead when it used an api key.
+type Principal struct {
+	UserID    int64
+	Role      string
+	Scopes    []string
+	TokenID   string
+	SessionID string
+	ExpiresAt time.Time
+	APIKeyID  int64
+}
+
+func (p Principal) HasRole(role string) bool {
+	return p.Role == role
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a context carrying p, see PrincipalFromContext.
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of an authenticated request.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
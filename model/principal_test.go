@@ -0,0 +1,27 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+func TestRoleScopes(t *testing.T) {
+	for _, role := range []string{model.ROLE_USER, model.ROLE_ADMIN} {
+		scopes := model.RoleScopes(role)
+		p := model.Principal{Role: role, Scopes: scopes}
+		if !p.HasScope(model.SCOPE_IMAGES_READ) || !p.HasScope(model.SCOPE_IMAGES_WRITE) || !p.HasScope(model.SCOPE_IMAGES_TRANSFORM) {
+			t.Fatalf("%s: error expected every image scope, but got %v", role, scopes)
+		}
+	}
+	if scopes := model.RoleScopes("guest"); len(scopes) != 0 {
+		t.Fatalf("error expected no scopes, but got %v", scopes)
+	}
+
+	// the returned scopes can be changed without affecting the role
+	scopes := model.RoleScopes(model.ROLE_USER)
+	scopes[0] = "changed"
+	if model.RoleScopes(model.ROLE_USER)[0] != model.SCOPE_IMAGES_READ {
+		t.Fatalf("error expected %v, but got %v", model.SCOPE_IMAGES_READ, model.RoleScopes(model.ROLE_USER)[0])
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RenderRequest is the query of a render url,
+// "?w=300&h=200&fit=cover&fmt=jpeg&q=80". It is run as a resize, format and
+// compress pipeline, every parameter is optional.
+type RenderRequest struct {
+	Width   int64  `form:"w" json:"w"`
+	Height  int64  `form:"h" json:"h"`
+	Fit     string `form:"fit" json:"fit"`
+	Format  string `form:"fmt" json:"fmt"`
+	Quality int64  `form:"q" json:"q"`
+}
+
+func (r RenderRequest) Validate() error {
+	if r.Width < 0 || r.Height < 0 {
+		return fmt.Errorf("w and h can't be negative")
+	}
+	if r.Fit != "" && r.Width == 0 && r.Height == 0 {
+		return fmt.Errorf("fit needs w or h")
+	}
+	return nil
+}
+
+// Query returns the url query of the request, without the unset params.
+func (r RenderRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Width != 0 {
+		query.Set("w", strconv.FormatInt(r.Width, 10))
+	}
+	if r.Height != 0 {
+		query.Set("h", strconv.FormatInt(r.Height, 10))
+	}
+	if r.Fit != "" {
+		query.Set("fit", r.Fit)
+	}
+	if r.Format != "" {
+		query.Set("fmt", r.Format)
+	}
+	if r.Quality != 0 {
+		query.Set("q", strconv.FormatInt(r.Quality, 10))
+	}
+	return query
+}
+
+// ToTransformRequest returns the pipeline the render runs.
+func (r RenderRequest) ToTransformRequest() ImageTransformRequestOpts {
+	steps := []TransformStep{}
+	if r.Width != 0 || r.Height != 0 {
+		steps = append(steps, NewTransformStep(OP_RESIZE, ResizeTransformRequest{Width: r.Width, Height: r.Height, Mode: r.Fit}))
+	}
+	if r.Format != "" {
+		format := r.Format
+		if format == "jpg" {
+			format = "jpeg"
+		}
+		steps = append(steps, NewTransformStep(OP_FORMAT, FormatTransformRequest{Format: format}))
+	}
+	if r.Quality != 0 {
+		steps = append(steps, NewTransformStep(OP_COMPRESS, CompressTransformRequest{Quality: r.Quality}))
+	}
+	return ImageTransformRequestOpts{Pipeline: steps}
+}
+
+// RenderedImage is an encoded render, Hash identifies the source content and
+// the applied steps.
+type RenderedImage struct {
+	Data   []byte
+	Format string
+	Hash   string
+}
+
+const (
+	SIGNED_RENDER   string = "render"
+	SIGNED_DOWNLOAD string = "download"
+)
+
+// SignURLRequest asks for a signed url rendering the image with Render, or
+// downloading the stored file. ExpiresIn is in seconds.
+type SignURLRequest struct {
+	Action    string        `json:"action"`
+	Render    RenderRequest `json:"render"`
+	ExpiresIn int64         `json:"expires_in"`
+}
+
+type SignedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+func TestRenderRequest(t *testing.T) {
+	vals, err := url.ParseQuery("w=300&h=200&fit=cover&fmt=jpg&q=80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := model.RenderRequest{}
+	if err := httputils.ParseURLValues(vals, &req); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := req.ToTransformRequest()
+	steps := opts.Steps()
+	expected := []string{
+		`{"op":"resize","params":{"width":300,"height":200,"mode":"cover","gravity":"","background":"","filter":""}}`,
+		`{"op":"format","params":{"format":"jpeg"}}`,
+		`{"op":"compress","params":{"quality":80,"compression_level":"","target_size":0}}`,
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("error expected %v steps, but got %v", len(expected), len(steps))
+	}
+	for i, step := range steps {
+		if got := `{"op":"` + step.Op + `","params":` + string(step.Params) + `}`; got != expected[i] {
+			t.Fatalf("error expected %v, but got %v", expected[i], got)
+		}
+	}
+
+	if err := (model.RenderRequest{Fit: model.RESIZE_COVER}).Validate(); err == nil {
+		t.Fatal("error expected fit without w or h to fail")
+	}
+	opts = model.RenderRequest{}.ToTransformRequest()
+	if steps := opts.Steps(); len(steps) != 0 {
+		t.Fatalf("error expected no steps, but got %v", steps)
+	}
+}
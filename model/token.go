@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// RefreshToken is a stored refresh token, only the hash of the token itself
+// is kept. Refreshing revokes the token and issues the next one of the same
+// session.
+type RefreshToken struct {
+	ID        int64      `db:"id"`
+	UserID    int64      `db:"user_id"`
+	SessionID string     `db:"session_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
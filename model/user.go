@@ -9,8 +9,42 @@ type User struct {
 	ID       int64  `db:"id"`
 	Email    string `db:"email"`
 	Password string `db:"password"`
+	Role     string `db:"role"`
+	// Disabled users can't log in and their tokens are rejected.
+	Disabled bool `db:"disabled"`
+}
+
+func (u User) ToUserResponse() UserResponse {
+	return UserResponse{
+		ID:       u.ID,
+		Email:    u.Email,
+		Role:     u.Role,
+		Disabled: u.Disabled,
+	}
+}
+
+type UserResponse struct {
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+type UserResponses []UserResponse
+
+type Users []User
+
+func (u Users) ToUserResponses() UserResponses {
+	var userResponses UserResponses
+	for _, user := range u {
+		userResponses = append(userResponses, user.ToUserResponse())
+	}
+	return userResponses
 }
 
 type AutheticationResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
 }
@@ -0,0 +1,145 @@
+package apikeyrepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var apiKeyColumns = []string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+type APIKeyRepoImpl struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) APIKeyRepo {
+	return &APIKeyRepoImpl{db: db}
+}
+
+func (r *APIKeyRepoImpl) CreateAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+	var expiresAt any
+	if key.ExpiresAt != nil {
+		expiresAt = key.ExpiresAt.UTC()
+	}
+	sq := squirrel.Insert("api_keys").
+		Columns("user_id", "name", "prefix", "key_hash", "scopes", "expires_at").
+		Values(key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, expiresAt).
+		Suffix("RETURNING " + strings.Join(apiKeyColumns, ", "))
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	var created model.APIKey
+	if err := stmt.QueryRowxContext(ctx, args...).StructScan(&created); err != nil {
+		return model.APIKey{}, err
+	}
+	return created, nil
+}
+
+func (r *APIKeyRepoImpl) GetAPIKeyByPrefix(ctx context.Context, prefix string) (model.APIKey, error) {
+	sq := squirrel.Select(apiKeyColumns...).From("api_keys").Where(squirrel.Eq{"prefix": prefix})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	var key model.APIKey
+	if err := stmt.QueryRowxContext(ctx, args...).StructScan(&key); err != nil {
+		return model.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepoImpl) GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error) {
+	sq := squirrel.Select(apiKeyColumns...).From("api_keys").
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("id")
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryxContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var keys []model.APIKey
+
+	for rows.Next() {
+		var key model.APIKey
+		if err := rows.StructScan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepoImpl) RevokeAPIKey(ctx context.Context, userID int64, id int64) error {
+	sq := squirrel.Update("api_keys").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id, "user_id": userID, "revoked_at": nil})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *APIKeyRepoImpl) TouchAPIKey(ctx context.Context, id int64) error {
+	sq := squirrel.Update("api_keys").
+		Set("last_used_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		return err
+	}
+	return nil
+}
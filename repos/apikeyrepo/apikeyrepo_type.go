@@ -0,0 +1,17 @@
+package apikeyrepo
+
+import (
+	"context"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+type APIKeyRepo interface {
+	CreateAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (model.APIKey, error)
+	GetAPIKeys(ctx context.Context, userID int64) ([]model.APIKey, error)
+	// RevokeAPIKey returns sql.ErrNoRows when the user has no such key that
+	// isn't revoked yet.
+	RevokeAPIKey(ctx context.Context, userID int64, id int64) error
+	TouchAPIKey(ctx context.Context, id int64) error
+}
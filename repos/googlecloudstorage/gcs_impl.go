@@ -5,16 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"image"
-	_ "image/jpeg"
 	"io"
-	"log"
 
 	"cloud.google.com/go/iam"
 	"cloud.google.com/go/iam/apiv1/iampb"
 	"cloud.google.com/go/storage"
 	"github.com/ARF-DEV/image-processing-api/configs"
 	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
 	"google.golang.org/api/iterator"
 )
 
@@ -23,10 +22,10 @@ type GoogleCloudStorageRepoImpl struct {
 	config *configs.Config
 }
 
-func New(ctx context.Context, cfg *configs.Config) GoogleCloudStorageRepo {
+func New(ctx context.Context, cfg *configs.Config) (storagerepo.StorageRepo, error) {
 	s, err := storage.NewClient(ctx)
 	if err != nil {
-		log.Fatal("error when creating gcs client: ", err)
+		return nil, fmt.Errorf("error when creating gcs client: %w", err)
 	}
 	isExists := false
 	itr := s.Buckets(ctx, cfg.GOOGLE_PROJECT_ID)
@@ -36,7 +35,8 @@ func New(ctx context.Context, cfg *configs.Config) GoogleCloudStorageRepo {
 			if errors.Is(err, iterator.Done) {
 				break
 			}
-			log.Fatal("error when iterating gcs bucket: ", err)
+			s.Close()
+			return nil, fmt.Errorf("error when iterating gcs bucket: %w", err)
 		}
 		if attr.Name == cfg.GCS_BUCKET_NAME {
 			isExists = true
@@ -51,14 +51,16 @@ func New(ctx context.Context, cfg *configs.Config) GoogleCloudStorageRepo {
 			Location: "ASIA-SOUTHEAST1",
 		})
 		if err != nil {
-			log.Fatal("error when creating gcs bucket: ", err)
+			s.Close()
+			return nil, fmt.Errorf("error when creating gcs bucket: %w", err)
 		}
 		fmt.Println("bucket created")
 	}
 
 	policy, err := bucket.IAM().V3().Policy(ctx)
 	if err != nil {
-		log.Fatal("error when getting IAM policy: ", err)
+		s.Close()
+		return nil, fmt.Errorf("error when getting IAM policy: %w", err)
 	}
 
 	policy.Bindings = append(policy.Bindings, &iampb.Binding{
@@ -67,13 +69,14 @@ func New(ctx context.Context, cfg *configs.Config) GoogleCloudStorageRepo {
 	})
 
 	if err := bucket.IAM().V3().SetPolicy(ctx, policy); err != nil {
-		log.Fatal("error when setting IAM policy: ", err)
+		s.Close()
+		return nil, fmt.Errorf("error when setting IAM policy: %w", err)
 	}
 
 	return &GoogleCloudStorageRepoImpl{
 		client: s,
 		config: cfg,
-	}
+	}, nil
 }
 
 func (r *GoogleCloudStorageRepoImpl) CreateBucket(ctx context.Context) error {
@@ -122,13 +125,26 @@ func (r *GoogleCloudStorageRepoImpl) LoadImage(ctx context.Context, img model.Im
 		return model.ImageInfo{}, err
 	}
 
-	loadedImage, format, err := image.Decode(&imageBuf)
+	return imageutils.Decode(&imageBuf)
+}
+
+func (r *GoogleCloudStorageRepoImpl) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	rc, err := r.client.Bucket(r.config.GCS_BUCKET_NAME).Object(name).NewReader(ctx)
 	if err != nil {
-		return model.ImageInfo{}, err
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %s", storagerepo.ErrObjectNotFound, name)
+		}
+		return nil, err
 	}
+	defer rc.Close()
 
-	return model.ImageInfo{
-		Image:  loadedImage,
-		Format: format,
-	}, nil
+	return io.ReadAll(rc)
+}
+
+func (r *GoogleCloudStorageRepoImpl) DeleteObject(ctx context.Context, name string) error {
+	err := r.client.Bucket(r.config.GCS_BUCKET_NAME).Object(name).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return err
+	}
+	return nil
 }
@@ -2,12 +2,23 @@ package imagerepo
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/ARF-DEV/image-processing-api/model"
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 )
 
+// images uploaded before ownership and attributes were introduced have no
+// owner and are not described
+var imageColumns = []string{
+	"id", "url", "COALESCE(owner_id, 0) AS owner_id", "COALESCE(original_filename, '') AS original_filename",
+	"COALESCE(transform_hash, '') AS transform_hash",
+	"COALESCE(width, 0) AS width", "COALESCE(height, 0) AS height", "COALESCE(format, '') AS format",
+	"COALESCE(byte_size, 0) AS byte_size", "COALESCE(color_model, '') AS color_model",
+	"COALESCE(has_alpha, false) AS has_alpha", "COALESCE(content_hash, '') AS content_hash", "exif",
+}
+
 type ImageRepoImpl struct {
 	db *sqlx.DB
 }
@@ -17,7 +28,10 @@ func New(db *sqlx.DB) ImageRepo {
 }
 
 func (r ImageRepoImpl) SaveImage(ctx context.Context, image model.Image) (int64, error) {
-	sq := squirrel.Insert("images").Columns("url").Values(image.URL).Suffix("RETURNING id")
+	sq := squirrel.Insert("images").
+		Columns("url", "owner_id", "original_filename", "transform_hash", "width", "height", "format", "byte_size", "color_model", "has_alpha", "content_hash", "exif").
+		Values(image.URL, image.OwnerID, image.OriginalFilename, nullString(image.TransformHash), image.Width, image.Height, image.Format, image.ByteSize, image.ColorModel, image.HasAlpha, image.ContentHash, image.EXIF).
+		Suffix("RETURNING id")
 	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
 		return 0, err
@@ -37,9 +51,28 @@ func (r ImageRepoImpl) SaveImage(ctx context.Context, image model.Image) (int64,
 	return id, nil
 }
 
-func (r *ImageRepoImpl) GetImages(ctx context.Context, page, limit int64) ([]model.Image, error) {
+func (r *ImageRepoImpl) GetImages(ctx context.Context, ownerID, page, limit int64) ([]model.Image, error) {
+	return r.getImages(ctx, squirrel.Eq{"owner_id": ownerID}, page, limit)
+}
+
+func (r *ImageRepoImpl) CountImages(ctx context.Context, ownerID int64) (int64, error) {
+	return r.countImages(ctx, squirrel.Eq{"owner_id": ownerID})
+}
+
+func (r *ImageRepoImpl) GetAllImages(ctx context.Context, ownerID, page, limit int64) ([]model.Image, error) {
+	return r.getImages(ctx, ownerFilter(ownerID), page, limit)
+}
+
+func (r *ImageRepoImpl) CountAllImages(ctx context.Context, ownerID int64) (int64, error) {
+	return r.countImages(ctx, ownerFilter(ownerID))
+}
+
+func (r *ImageRepoImpl) getImages(ctx context.Context, where squirrel.Eq, page, limit int64) ([]model.Image, error) {
 	offset := (page - 1) * limit
-	sq := squirrel.Select("id", "url").From("images").Limit(uint64(limit)).Offset(uint64(offset))
+	sq := squirrel.Select(imageColumns...).From("images").
+		Where(where).
+		OrderBy("id").
+		Limit(uint64(limit)).Offset(uint64(offset))
 	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
 		return nil, err
@@ -69,8 +102,8 @@ func (r *ImageRepoImpl) GetImages(ctx context.Context, page, limit int64) ([]mod
 	return images, nil
 }
 
-func (r *ImageRepoImpl) CountImages(ctx context.Context) (int64, error) {
-	sq := squirrel.Select("count(id)").From("images")
+func (r *ImageRepoImpl) countImages(ctx context.Context, where squirrel.Eq) (int64, error) {
+	sq := squirrel.Select("count(id)").From("images").Where(where)
 
 	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
@@ -90,8 +123,49 @@ func (r *ImageRepoImpl) CountImages(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
-func (r *ImageRepoImpl) GetImage(ctx context.Context, id int64) (model.Image, error) {
-	sq := squirrel.Select("id", "url").From("images").Where(squirrel.Eq{"id": id})
+func (r *ImageRepoImpl) GetImage(ctx context.Context, ownerID, id int64) (model.Image, error) {
+	return r.getImage(ctx, squirrel.Eq{"id": id, "owner_id": ownerID})
+}
+
+func (r *ImageRepoImpl) FindImageByContentHash(ctx context.Context, ownerID int64, hash string) (model.Image, error) {
+	return r.getImage(ctx, squirrel.Eq{"owner_id": ownerID, "content_hash": hash})
+}
+
+func (r *ImageRepoImpl) FindImageByTransformHash(ctx context.Context, ownerID int64, hash string) (model.Image, error) {
+	return r.getImage(ctx, squirrel.Eq{"owner_id": ownerID, "transform_hash": hash})
+}
+
+func (r *ImageRepoImpl) GetImageByID(ctx context.Context, id int64) (model.Image, error) {
+	return r.getImage(ctx, squirrel.Eq{"id": id})
+}
+
+func (r *ImageRepoImpl) DeleteImage(ctx context.Context, id int64) error {
+	sq := squirrel.Delete("images").Where(squirrel.Eq{"id": id})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// getImage returns the oldest image matching where.
+func (r *ImageRepoImpl) getImage(ctx context.Context, where squirrel.Eq) (model.Image, error) {
+	sq := squirrel.Select(imageColumns...).From("images").Where(where).OrderBy("id").Limit(1)
 
 	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
@@ -110,3 +184,19 @@ func (r *ImageRepoImpl) GetImage(ctx context.Context, id int64) (model.Image, er
 
 	return image, nil
 }
+
+// ownerFilter matches the images of ownerID, or every image when it is 0.
+func ownerFilter(ownerID int64) squirrel.Eq {
+	if ownerID == 0 {
+		return squirrel.Eq{}
+	}
+	return squirrel.Eq{"owner_id": ownerID}
+}
+
+// nullString stores empty strings as NULL.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
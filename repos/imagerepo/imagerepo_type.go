@@ -8,7 +8,19 @@ import (
 
 type ImageRepo interface {
 	SaveImage(ctx context.Context, image model.Image) (int64, error)
-	GetImages(ctx context.Context, page int64, limit int64) ([]model.Image, error)
-	CountImages(ctx context.Context) (int64, error)
-	GetImage(ctx context.Context, id int64) (model.Image, error)
+	GetImages(ctx context.Context, ownerID int64, page int64, limit int64) ([]model.Image, error)
+	CountImages(ctx context.Context, ownerID int64) (int64, error)
+	GetImage(ctx context.Context, ownerID int64, id int64) (model.Image, error)
+	// FindImageByContentHash and FindImageByTransformHash return the oldest
+	// matching image of the owner, or sql.ErrNoRows.
+	FindImageByContentHash(ctx context.Context, ownerID int64, hash string) (model.Image, error)
+	FindImageByTransformHash(ctx context.Context, ownerID int64, hash string) (model.Image, error)
+	// GetAllImages, CountAllImages, GetImageByID and DeleteImage are not
+	// scoped to an owner, they are only used by admins. GetAllImages and
+	// CountAllImages only include the images of ownerID unless it is 0.
+	GetAllImages(ctx context.Context, ownerID int64, page int64, limit int64) ([]model.Image, error)
+	CountAllImages(ctx context.Context, ownerID int64) (int64, error)
+	GetImageByID(ctx context.Context, id int64) (model.Image, error)
+	// DeleteImage returns sql.ErrNoRows when the image doesn't exist.
+	DeleteImage(ctx context.Context, id int64) error
 }
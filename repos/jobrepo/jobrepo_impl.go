@@ -0,0 +1,90 @@
+package jobrepo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var jobColumns = []string{"id", "image_id", "owner_id", "status", "error", "COALESCE(result_image_id, 0) AS result_image_id", "encoding", "created_at", "updated_at"}
+
+type JobRepoImpl struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) JobRepo {
+	return &JobRepoImpl{db: db}
+}
+
+func (r *JobRepoImpl) CreateJob(ctx context.Context, job model.TransformJob) (model.TransformJob, error) {
+	sq := squirrel.Insert("transform_jobs").
+		Columns("image_id", "owner_id", "status").
+		Values(job.ImageID, job.OwnerID, job.Status).
+		Suffix("RETURNING " + strings.Join(jobColumns, ", "))
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return model.TransformJob{}, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return model.TransformJob{}, err
+	}
+
+	var created model.TransformJob
+	if err := stmt.QueryRowxContext(ctx, args...).StructScan(&created); err != nil {
+		return model.TransformJob{}, err
+	}
+	return created, nil
+}
+
+func (r *JobRepoImpl) GetJob(ctx context.Context, ownerID int64, id int64) (model.TransformJob, error) {
+	sq := squirrel.Select(jobColumns...).From("transform_jobs").Where(squirrel.Eq{"id": id, "owner_id": ownerID})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return model.TransformJob{}, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return model.TransformJob{}, err
+	}
+
+	var job model.TransformJob
+	if err := stmt.QueryRowxContext(ctx, args...).StructScan(&job); err != nil {
+		return model.TransformJob{}, err
+	}
+	return job, nil
+}
+
+func (r *JobRepoImpl) UpdateJob(ctx context.Context, job model.TransformJob) error {
+	var resultImageID any
+	if job.ResultImageID != 0 {
+		resultImageID = job.ResultImageID
+	}
+
+	sq := squirrel.Update("transform_jobs").
+		Set("status", job.Status).
+		Set("error", job.Error).
+		Set("result_image_id", resultImageID).
+		Set("encoding", job.Encoding).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": job.ID})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package jobrepo
+
+import (
+	"context"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+type JobRepo interface {
+	CreateJob(ctx context.Context, job model.TransformJob) (model.TransformJob, error)
+	GetJob(ctx context.Context, ownerID int64, id int64) (model.TransformJob, error)
+	UpdateJob(ctx context.Context, job model.TransformJob) error
+}
@@ -0,0 +1,147 @@
+package localstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+)
+
+type LocalStorageRepoImpl struct {
+	root   string
+	bucket string
+}
+
+func New(cfg *configs.Config) (LocalStorageRepo, error) {
+	root, err := filepath.Abs(cfg.LOCAL_STORAGE_PATH)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &LocalStorageRepoImpl{
+		root:   root,
+		bucket: cfg.LOCAL_STORAGE_BUCKET,
+	}
+	if err := r.CreateBucket(context.Background()); err != nil {
+		return nil, fmt.Errorf("error when creating local bucket: %w", err)
+	}
+	return r, nil
+}
+
+func (r *LocalStorageRepoImpl) CreateBucket(ctx context.Context) error {
+	return os.MkdirAll(filepath.Join(r.root, r.bucket), 0o755)
+}
+
+func (r *LocalStorageRepoImpl) UploadImage(ctx context.Context, req model.UploadImageRequest) (string, error) {
+	path, err := r.objectPath(r.bucket, req.Name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	// O_EXCL mirrors the DoesNotExist precondition used for GCS uploads
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("error when creating object: %w", err)
+	}
+	if _, err := io.Copy(f, req.Reader); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("error when writing object: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("error when closing object: %w", err)
+	}
+
+	return fmt.Sprintf("/%s/%s", r.bucket, req.Name), nil
+}
+
+func (r *LocalStorageRepoImpl) LoadImage(ctx context.Context, img model.Image) (model.ImageInfo, error) {
+	path, err := r.objectPath(img.GetBucket(), img.GetObject())
+	if err != nil {
+		return model.ImageInfo{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return model.ImageInfo{}, err
+	}
+	defer f.Close()
+
+	return imageutils.Decode(f)
+}
+
+func (r *LocalStorageRepoImpl) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	path, err := r.objectPath(r.bucket, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", storagerepo.ErrObjectNotFound, name)
+	}
+	return data, err
+}
+
+func (r *LocalStorageRepoImpl) DeleteObject(ctx context.Context, name string) error {
+	path, err := r.objectPath(r.bucket, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (r *LocalStorageRepoImpl) Close() {}
+
+func (r *LocalStorageRepoImpl) FileHandler() http.Handler {
+	return http.FileServer(fileOnlyFS{http.Dir(r.root)})
+}
+
+// objectPath resolves an object inside the storage root, rejecting names that
+// would escape it.
+func (r *LocalStorageRepoImpl) objectPath(bucket, name string) (string, error) {
+	path := filepath.Join(r.root, bucket, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, r.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object name %q", name)
+	}
+	return path, nil
+}
+
+// fileOnlyFS hides directories so the file server never lists bucket contents.
+type fileOnlyFS struct {
+	fs http.FileSystem
+}
+
+func (f fileOnlyFS) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return file, nil
+}
@@ -0,0 +1,13 @@
+package localstorage
+
+import (
+	"net/http"
+
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+)
+
+type LocalStorageRepo interface {
+	storagerepo.StorageRepo
+	// FileHandler serves the stored objects, rooted at the storage directory.
+	FileHandler() http.Handler
+}
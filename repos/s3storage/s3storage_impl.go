@@ -0,0 +1,143 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// publicReadPolicy lets anyone read the objects, like the allUsers
+// objectViewer binding set on the GCS bucket.
+const publicReadPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [{
+		"Effect": "Allow",
+		"Principal": {"AWS": ["*"]},
+		"Action": ["s3:GetObject"],
+		"Resource": ["arn:aws:s3:::%s/*"]
+	}]
+}`
+
+type S3StorageRepoImpl struct {
+	client *minio.Client
+	config *configs.Config
+}
+
+func New(ctx context.Context, cfg *configs.Config) (storagerepo.StorageRepo, error) {
+	lookup := minio.BucketLookupAuto
+	if cfg.S3_USE_PATH_STYLE {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(cfg.S3_ENDPOINT, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.S3_ACCESS_KEY_ID, cfg.S3_SECRET_ACCESS_KEY, ""),
+		Secure:       cfg.S3_USE_SSL,
+		Region:       cfg.S3_REGION,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when creating s3 client: %w", err)
+	}
+
+	r := &S3StorageRepoImpl{
+		client: client,
+		config: cfg,
+	}
+
+	isExists, err := client.BucketExists(ctx, cfg.S3_BUCKET_NAME)
+	if err != nil {
+		return nil, fmt.Errorf("error when checking s3 bucket: %w", err)
+	}
+	if !isExists {
+		if err := r.CreateBucket(ctx); err != nil {
+			return nil, fmt.Errorf("error when creating s3 bucket: %w", err)
+		}
+		fmt.Println("bucket created")
+	}
+
+	if err := client.SetBucketPolicy(ctx, cfg.S3_BUCKET_NAME, fmt.Sprintf(publicReadPolicy, cfg.S3_BUCKET_NAME)); err != nil {
+		return nil, fmt.Errorf("error when setting bucket policy: %w", err)
+	}
+
+	return r, nil
+}
+
+func (r *S3StorageRepoImpl) CreateBucket(ctx context.Context) error {
+	return r.client.MakeBucket(ctx, r.config.S3_BUCKET_NAME, minio.MakeBucketOptions{
+		Region: r.config.S3_REGION,
+	})
+}
+
+func (r *S3StorageRepoImpl) UploadImage(ctx context.Context, req model.UploadImageRequest) (string, error) {
+	// S3 has no portable "does not exist" precondition, check before writing
+	_, err := r.client.StatObject(ctx, r.config.S3_BUCKET_NAME, req.Name, minio.StatObjectOptions{})
+	if err == nil {
+		return "", fmt.Errorf("error when uploading to bucket: object %s already exists", req.Name)
+	}
+	if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return "", fmt.Errorf("error when checking object: %w", err)
+	}
+
+	// buffering gives the client the object size, so small images are sent
+	// in a single request instead of a multipart upload
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, req.Reader); err != nil {
+		return "", fmt.Errorf("error when reading image: %w", err)
+	}
+
+	_, err = r.client.PutObject(ctx, r.config.S3_BUCKET_NAME, req.Name, &buf, int64(buf.Len()), minio.PutObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error when uploading to bucket: %w", err)
+	}
+
+	publicUrl := fmt.Sprintf("/%s/%s", r.config.S3_BUCKET_NAME, req.Name)
+	return publicUrl, nil
+}
+
+func (r *S3StorageRepoImpl) LoadImage(ctx context.Context, img model.Image) (model.ImageInfo, error) {
+	obj, err := r.client.GetObject(ctx, img.GetBucket(), img.GetObject(), minio.GetObjectOptions{})
+	if err != nil {
+		return model.ImageInfo{}, err
+	}
+	defer obj.Close()
+
+	var imageBuf bytes.Buffer
+	if _, err := io.Copy(&imageBuf, obj); err != nil {
+		return model.ImageInfo{}, err
+	}
+
+	return imageutils.Decode(&imageBuf)
+}
+
+func (r *S3StorageRepoImpl) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	obj, err := r.client.GetObject(ctx, r.config.S3_BUCKET_NAME, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	// the object is only requested on the first read
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("%w: %s", storagerepo.ErrObjectNotFound, name)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// DeleteObject relies on S3 treating deletes of missing objects as successful.
+func (r *S3StorageRepoImpl) DeleteObject(ctx context.Context, name string) error {
+	return r.client.RemoveObject(ctx, r.config.S3_BUCKET_NAME, name, minio.RemoveObjectOptions{})
+}
+
+func (r *S3StorageRepoImpl) Close() {}
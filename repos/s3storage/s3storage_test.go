@@ -0,0 +1,175 @@
+package s3storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/s3storage"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+)
+
+// fakeS3 implements the handful of path-style S3 calls the repo makes.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string][]byte
+	policy  string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, object, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if object == "" {
+		switch {
+		case r.Method == http.MethodHead:
+			if !f.buckets[bucket] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && r.URL.Query().Has("policy"):
+			body, _ := io.ReadAll(r.Body)
+			f.policy = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPut:
+			f.buckets[bucket] = true
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	key := bucket + "/" + object
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		data, found := f.objects[key]
+		if !found {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeChunked(body)
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// decodeChunked strips the aws-chunked framing the client uses to sign
+// payloads sent over plain http.
+func decodeChunked(body []byte) []byte {
+	var data []byte
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || size == 0 {
+			break
+		}
+		data = append(data, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+	return data
+}
+
+func TestUploadAndLoadImage(t *testing.T) {
+	fake := &fakeS3{buckets: map[string]bool{}, objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := &configs.Config{
+		S3_ENDPOINT:          strings.TrimPrefix(server.URL, "http://"),
+		S3_REGION:            "us-east-1",
+		S3_ACCESS_KEY_ID:     "access",
+		S3_SECRET_ACCESS_KEY: "secret",
+		S3_BUCKET_NAME:       "images",
+		S3_USE_PATH_STYLE:    true,
+	}
+	ctx := context.Background()
+	repo, err := s3storage.New(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fake.buckets["images"] {
+		t.Fatal("error expected bucket to be created")
+	}
+	if !strings.Contains(fake.policy, "arn:aws:s3:::images/*") {
+		t.Fatalf("error expected public read policy, but got %v", fake.policy)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	url, err := repo.UploadImage(ctx, model.UploadImageRequest{Reader: bytes.NewReader(buf.Bytes()), Name: "users/1/photo.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/images/users/1/photo.png" {
+		t.Fatalf("error expected %v, but got %v", "/images/users/1/photo.png", url)
+	}
+
+	if _, err := repo.UploadImage(ctx, model.UploadImageRequest{Reader: bytes.NewReader(buf.Bytes()), Name: "users/1/photo.png"}); err == nil {
+		t.Fatal("error expected uploading an existing object to fail")
+	}
+
+	img := model.Image{URL: url}
+	if img.GetBucket() != "images" || img.GetObject() != "users/1/photo.png" {
+		t.Fatalf("error expected images/users/1/photo.png, but got %v/%v", img.GetBucket(), img.GetObject())
+	}
+	info, err := repo.LoadImage(ctx, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != "png" {
+		t.Fatalf("error expected %v, but got %v", "png", info.Format)
+	}
+	if info.Image.Bounds() != image.Rect(0, 0, 4, 3) {
+		t.Fatalf("error expected %v, but got %v", image.Rect(0, 0, 4, 3), info.Image.Bounds())
+	}
+
+	data, err := repo.ReadObject(ctx, "users/1/photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatalf("error expected %v bytes, but got %v", buf.Len(), len(data))
+	}
+	if _, err := repo.ReadObject(ctx, "users/1/missing.png"); !errors.Is(err, storagerepo.ErrObjectNotFound) {
+		t.Fatalf("error expected %v, but got %v", storagerepo.ErrObjectNotFound, err)
+	}
+
+	if err := repo.DeleteObject(ctx, "users/1/photo.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ReadObject(ctx, "users/1/photo.png"); !errors.Is(err, storagerepo.ErrObjectNotFound) {
+		t.Fatalf("error expected %v, but got %v", storagerepo.ErrObjectNotFound, err)
+	}
+}
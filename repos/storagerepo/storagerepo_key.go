@@ -0,0 +1,20 @@
+package storagerepo
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// NewObjectKey returns a unique object name under the owner prefix,
+// "users/<owner id>/<uuid>.<ext>". Client supplied names are never used as
+// object names, they are kept as model.Image.OriginalFilename.
+func NewObjectKey(ownerID int64, ext string) string {
+	return fmt.Sprintf("users/%d/%s.%s", ownerID, uuid.NewString(), ext)
+}
+
+// RenderKey returns the object name caching a rendered variant of an image of
+// the owner, hash identifies the source content and the applied steps.
+func RenderKey(ownerID int64, hash string) string {
+	return fmt.Sprintf("users/%d/renders/%s", ownerID, hash)
+}
@@ -0,0 +1,25 @@
+package storagerepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+// ErrObjectNotFound is wrapped by ReadObject when the object doesn't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// StorageRepo is implemented by every object storage backend. Uploaded objects
+// are addressed by a "/<bucket>/<object>" url that model.Image understands.
+type StorageRepo interface {
+	CreateBucket(ctx context.Context) error
+	UploadImage(ctx context.Context, req model.UploadImageRequest) (string, error)
+	LoadImage(ctx context.Context, image model.Image) (model.ImageInfo, error)
+	// ReadObject returns the raw bytes of an object of the configured bucket.
+	ReadObject(ctx context.Context, name string) ([]byte, error)
+	// DeleteObject removes an object of the configured bucket, deleting an
+	// object that doesn't exist is not an error.
+	DeleteObject(ctx context.Context, name string) error
+	Close()
+}
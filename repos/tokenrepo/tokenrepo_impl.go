@@ -0,0 +1,168 @@
+package tokenrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var refreshTokenColumns = []string{"id", "user_id", "session_id", "token_hash", "expires_at", "revoked_at", "created_at"}
+
+type TokenRepoImpl struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) TokenRepo {
+	return &TokenRepoImpl{db: db}
+}
+
+func (r *TokenRepoImpl) CreateRefreshToken(ctx context.Context, token model.RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+func (r *TokenRepoImpl) GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
+	sq := squirrel.Select(refreshTokenColumns...).From("refresh_tokens").Where(squirrel.Eq{"token_hash": tokenHash})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	var token model.RefreshToken
+	if err := stmt.QueryRowxContext(ctx, args...).StructScan(&token); err != nil {
+		return model.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *TokenRepoImpl) RotateRefreshToken(ctx context.Context, oldID int64, next model.RefreshToken) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sq := squirrel.Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": oldID, "revoked_at": nil})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	// a concurrent refresh already rotated it
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TokenRepoImpl) RevokeSession(ctx context.Context, sessionID string) error {
+	return r.revokeRefreshTokens(ctx, squirrel.Eq{"session_id": sessionID, "revoked_at": nil})
+}
+
+func (r *TokenRepoImpl) RevokeUserSessions(ctx context.Context, userID int64) error {
+	return r.revokeRefreshTokens(ctx, squirrel.Eq{"user_id": userID, "revoked_at": nil})
+}
+
+func (r *TokenRepoImpl) revokeRefreshTokens(ctx context.Context, where squirrel.Eq) error {
+	sq := squirrel.Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Where(where)
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *TokenRepoImpl) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// expired tokens are rejected anyway, they don't need to be kept
+	sq := squirrel.Delete("revoked_tokens").Where(squirrel.LtOrEq{"expires_at": time.Now().UTC()})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	insert := squirrel.Insert("revoked_tokens").
+		Columns("jti", "expires_at").
+		Values(jti, expiresAt.UTC()).
+		Suffix("ON CONFLICT (jti) DO NOTHING")
+	query, args, err = insert.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TokenRepoImpl) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	sq := squirrel.Select("count(jti)").From("revoked_tokens").Where(squirrel.Eq{"jti": jti})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := stmt.QueryRowxContext(ctx, args...).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func insertRefreshToken(ctx context.Context, db sqlx.ExtContext, token model.RefreshToken) error {
+	sq := squirrel.Insert("refresh_tokens").
+		Columns("user_id", "session_id", "token_hash", "expires_at").
+		Values(token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt.UTC())
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	return nil
+}
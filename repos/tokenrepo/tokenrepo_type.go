@@ -0,0 +1,25 @@
+package tokenrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+type TokenRepo interface {
+	CreateRefreshToken(ctx context.Context, token model.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error)
+	// RotateRefreshToken revokes the token oldID and stores next in the same
+	// transaction. It fails with sql.ErrNoRows when oldID was already revoked,
+	// so a token can only be rotated once.
+	RotateRefreshToken(ctx context.Context, oldID int64, next model.RefreshToken) error
+	// RevokeSession revokes every refresh token of the session.
+	RevokeSession(ctx context.Context, sessionID string) error
+	// RevokeUserSessions revokes every refresh token of the user.
+	RevokeUserSessions(ctx context.Context, userID int64) error
+	// RevokeAccessToken keeps jti revoked until the token expires, revoked
+	// tokens that expired are deleted at the same time.
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
@@ -2,12 +2,15 @@ package userrepo
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/ARF-DEV/image-processing-api/model"
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 )
 
+var userColumns = []string{"id", "email", "password", "role", "disabled"}
+
 type UserRepoImpl struct {
 	db *sqlx.DB
 }
@@ -36,7 +39,26 @@ func (r *UserRepoImpl) CreateUser(ctx context.Context, user model.User) error {
 }
 
 func (r *UserRepoImpl) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
-	sq := squirrel.Select("id", "email", "password").From("users").Limit(1)
+	sq := squirrel.Select(userColumns...).From("users").Where(squirrel.Eq{"email": email}).Limit(1)
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return model.User{}, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	var res model.User
+	if err := stmt.QueryRowxContext(ctx, args...).StructScan(&res); err != nil {
+		return model.User{}, err
+	}
+	return res, nil
+}
+
+func (r *UserRepoImpl) GetUser(ctx context.Context, id int64) (model.User, error) {
+	sq := squirrel.Select(userColumns...).From("users").Where(squirrel.Eq{"id": id})
 	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
 		return model.User{}, err
@@ -53,3 +75,80 @@ func (r *UserRepoImpl) GetUserByEmail(ctx context.Context, email string) (model.
 	}
 	return res, nil
 }
+
+func (r *UserRepoImpl) GetUsers(ctx context.Context, page, limit int64) ([]model.User, error) {
+	offset := (page - 1) * limit
+	sq := squirrel.Select(userColumns...).From("users").
+		OrderBy("id").
+		Limit(uint64(limit)).Offset(uint64(offset))
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryxContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var users []model.User
+
+	for rows.Next() {
+		var user model.User
+		if err := rows.StructScan(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepoImpl) CountUsers(ctx context.Context) (int64, error) {
+	sq := squirrel.Select("count(id)").From("users")
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := stmt.QueryRowxContext(ctx, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *UserRepoImpl) SetUserDisabled(ctx context.Context, id int64, disabled bool) error {
+	sq := squirrel.Update("users").Set("disabled", disabled).Where(squirrel.Eq{"id": id})
+	query, args, err := sq.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := r.db.PreparexContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
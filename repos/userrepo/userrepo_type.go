@@ -9,4 +9,9 @@ import (
 type UserRepo interface {
 	CreateUser(ctx context.Context, user model.User) error
 	GetUserByEmail(ctx context.Context, email string) (model.User, error)
+	GetUser(ctx context.Context, id int64) (model.User, error)
+	GetUsers(ctx context.Context, page int64, limit int64) ([]model.User, error)
+	CountUsers(ctx context.Context) (int64, error)
+	// SetUserDisabled returns sql.ErrNoRows when the user doesn't exist.
+	SetUserDisabled(ctx context.Context, id int64, disabled bool) error
 }
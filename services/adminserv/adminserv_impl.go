@@ -0,0 +1,122 @@
+package adminserv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/imagerepo"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/repos/tokenrepo"
+	"github.com/ARF-DEV/image-processing-api/repos/userrepo"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type AdminServImpl struct {
+	resource  storagerepo.StorageRepo
+	userRepo  userrepo.UserRepo
+	tokenRepo tokenrepo.TokenRepo
+	imageRepo imagerepo.ImageRepo
+}
+
+func New(resource storagerepo.StorageRepo, userRepo userrepo.UserRepo, tokenRepo tokenrepo.TokenRepo, imageRepo imagerepo.ImageRepo) AdminServ {
+	return &AdminServImpl{
+		resource:  resource,
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		imageRepo: imageRepo,
+	}
+}
+
+func (s *AdminServImpl) GetUsers(ctx context.Context, page int64, limit int64) (model.UserResponses, *model.Meta, error) {
+	users, err := s.userRepo.GetUsers(ctx, page, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	total, err := s.userRepo.CountUsers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := model.Meta{
+		Page:      page,
+		Limit:     limit,
+		TotalData: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(limit))),
+	}
+	return model.Users(users).ToUserResponses(), &meta, nil
+}
+
+func (s *AdminServImpl) SetUserDisabled(ctx context.Context, adminID int64, id int64, disabled bool) (model.UserResponse, error) {
+	if disabled && adminID == id {
+		return model.UserResponse{}, fmt.Errorf("%w: admins can't disable their own account", httputils.ErrBadRequest)
+	}
+
+	if err := s.userRepo.SetUserDisabled(ctx, id, disabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.UserResponse{}, httputils.ErrNotFound
+		}
+		return model.UserResponse{}, err
+	}
+	if disabled {
+		// access tokens are rejected by the middleware while the user is disabled
+		if err := s.tokenRepo.RevokeUserSessions(ctx, id); err != nil {
+			return model.UserResponse{}, err
+		}
+	}
+
+	user, err := s.userRepo.GetUser(ctx, id)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+	return user.ToUserResponse(), nil
+}
+
+func (s *AdminServImpl) GetImages(ctx context.Context, ownerID int64, page int64, limit int64) (model.ImageResponses, *model.Meta, error) {
+	images, err := s.imageRepo.GetAllImages(ctx, ownerID, page, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	total, err := s.imageRepo.CountAllImages(ctx, ownerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := model.Meta{
+		Page:      page,
+		Limit:     limit,
+		TotalData: total,
+		TotalPage: int64(math.Ceil(float64(total) / float64(limit))),
+	}
+	return model.Images(images).ToImageResponses(configs.GetConfig()), &meta, nil
+}
+
+func (s *AdminServImpl) DeleteImage(ctx context.Context, id int64) error {
+	image, err := s.imageRepo.GetImageByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return httputils.ErrNotFound
+		}
+		return err
+	}
+
+	if err := s.imageRepo.DeleteImage(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return httputils.ErrNotFound
+		}
+		return err
+	}
+	// the row is gone so the image can't be reached anymore, a file left
+	// behind is only logged
+	if err := s.resource.DeleteObject(ctx, image.GetObject()); err != nil {
+		log.Println("error when deleting image object: ", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package adminserv
+
+import (
+	"context"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+// AdminServ manages every user and image, callers must check the caller is
+// an admin.
+type AdminServ interface {
+	GetUsers(ctx context.Context, page int64, limit int64) (model.UserResponses, *model.Meta, error)
+	// SetUserDisabled disables or enables the account id, disabling it also
+	// revokes its refresh tokens. Admins can't disable themselves.
+	SetUserDisabled(ctx context.Context, adminID int64, id int64, disabled bool) (model.UserResponse, error)
+	// GetImages lists the images of ownerID, or of every user when it is 0.
+	GetImages(ctx context.Context, ownerID int64, page int64, limit int64) (model.ImageResponses, *model.Meta, error)
+	// DeleteImage removes the image and its stored file.
+	DeleteImage(ctx context.Context, id int64) error
+}
@@ -0,0 +1,80 @@
+package apikeyserv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/apikeyrepo"
+	"github.com/ARF-DEV/image-processing-api/utils/apikeyutils"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type APIKeyServImpl struct {
+	apiKeyRepo apikeyrepo.APIKeyRepo
+}
+
+func New(apiKeyRepo apikeyrepo.APIKeyRepo) APIKeyServ {
+	return &APIKeyServImpl{apiKeyRepo: apiKeyRepo}
+}
+
+func (s *APIKeyServImpl) CreateAPIKey(ctx context.Context, principal model.Principal, req model.CreateAPIKeyRequest) (model.CreatedAPIKeyResponse, error) {
+	if err := req.Validate(); err != nil {
+		return model.CreatedAPIKeyResponse{}, fmt.Errorf("%w: %v", httputils.ErrBadRequest, err)
+	}
+	for _, scope := range req.Scopes {
+		if !principal.HasScope(scope) {
+			return model.CreatedAPIKeyResponse{}, fmt.Errorf("%w: the %s scope isn't granted to you", httputils.ErrForbidden, scope)
+		}
+	}
+
+	key, prefix, err := apikeyutils.NewAPIKey()
+	if err != nil {
+		return model.CreatedAPIKeyResponse{}, err
+	}
+	hash, err := apikeyutils.HashAPIKey(key)
+	if err != nil {
+		return model.CreatedAPIKeyResponse{}, err
+	}
+	apiKey := model.APIKey{
+		UserID:  principal.UserID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  req.Scopes,
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	created, err := s.apiKeyRepo.CreateAPIKey(ctx, apiKey)
+	if err != nil {
+		return model.CreatedAPIKeyResponse{}, err
+	}
+	return model.CreatedAPIKeyResponse{
+		APIKeyResponse: created.ToAPIKeyResponse(),
+		Key:            key,
+	}, nil
+}
+
+func (s *APIKeyServImpl) GetAPIKeys(ctx context.Context, userID int64) (model.APIKeyResponses, error) {
+	keys, err := s.apiKeyRepo.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return model.APIKeys(keys).ToAPIKeyResponses(), nil
+}
+
+func (s *APIKeyServImpl) RevokeAPIKey(ctx context.Context, userID int64, id int64) error {
+	if err := s.apiKeyRepo.RevokeAPIKey(ctx, userID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return httputils.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package apikeyserv
+
+import (
+	"context"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+type APIKeyServ interface {
+	// CreateAPIKey returns the new key, it is only stored hashed. principal
+	// must be granted every requested scope.
+	CreateAPIKey(ctx context.Context, principal model.Principal, req model.CreateAPIKeyRequest) (model.CreatedAPIKeyResponse, error)
+	GetAPIKeys(ctx context.Context, userID int64) (model.APIKeyResponses, error)
+	RevokeAPIKey(ctx context.Context, userID int64, id int64) error
+}
@@ -3,72 +3,106 @@ package imageserv
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
-	"image/color"
-	"image/draw"
-	"image/jpeg"
-	"image/png"
 	"io"
+	"log"
 	"math"
 	"mime/multipart"
-	"strings"
+	"net/url"
+	"slices"
+	"time"
 
 	"github.com/ARF-DEV/image-processing-api/configs"
 	"github.com/ARF-DEV/image-processing-api/model"
 	producerconsumer "github.com/ARF-DEV/image-processing-api/producer_consumer"
-	"github.com/ARF-DEV/image-processing-api/repos/googlecloudstorage"
 	"github.com/ARF-DEV/image-processing-api/repos/imagerepo"
-	"github.com/disintegration/imaging"
+	"github.com/ARF-DEV/image-processing-api/repos/jobrepo"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/transform"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+	"github.com/ARF-DEV/image-processing-api/utils/signutils"
 )
 
-const (
-	IMG_JPEG string = "jpeg"
-	IMG_PNG  string = "png"
-)
-
-type imageConvertFunc func(w io.Writer, image image.Image) error
-
 type ImageServImpl struct {
-	resource  googlecloudstorage.GoogleCloudStorageRepo
-	imageRepo imagerepo.ImageRepo
-	producer  *producerconsumer.Producer
+	resource    storagerepo.StorageRepo
+	imageRepo   imagerepo.ImageRepo
+	jobRepo     jobrepo.JobRepo
+	transformer transform.Transformer
+	producer    *producerconsumer.Producer
 }
 
-func New(resource googlecloudstorage.GoogleCloudStorageRepo, imageRepo imagerepo.ImageRepo, producer *producerconsumer.Producer) ImageServ {
+func New(resource storagerepo.StorageRepo, imageRepo imagerepo.ImageRepo, jobRepo jobrepo.JobRepo, transformer transform.Transformer, producer *producerconsumer.Producer) ImageServ {
 	return &ImageServImpl{
-		resource:  resource,
-		imageRepo: imageRepo,
-		producer:  producer,
+		resource:    resource,
+		imageRepo:   imageRepo,
+		jobRepo:     jobRepo,
+		transformer: transformer,
+		producer:    producer,
 	}
 }
 
-func (s *ImageServImpl) UploadImage(ctx context.Context, file multipart.File, header *multipart.FileHeader) error {
+// UploadImage stores a new image, or returns the image of the owner with the
+// same content when there is one.
+func (s *ImageServImpl) UploadImage(ctx context.Context, ownerID int64, file multipart.File, header *multipart.FileHeader) (model.ImageResponse, error) {
+	cfg := configs.GetConfig()
+	data, err := io.ReadAll(io.LimitReader(file, cfg.UPLOAD_MAX_BYTES+1))
+	if err != nil {
+		return model.ImageResponse{}, err
+	}
+	if err := validateUpload(cfg, data); err != nil {
+		return model.ImageResponse{}, err
+	}
+
+	if cfg.STRIP_GPS_ON_UPLOAD {
+		data = imageutils.StripGPS(data)
+	}
+	attributes, err := imageutils.Describe(data)
+	if err != nil {
+		return model.ImageResponse{}, fmt.Errorf("%w: invalid image: %v", httputils.ErrBadRequest, err)
+	}
+
+	existing, err := s.imageRepo.FindImageByContentHash(ctx, ownerID, attributes.ContentHash)
+	if err == nil {
+		return existing.ToImageResponse(cfg), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return model.ImageResponse{}, err
+	}
+
 	url, err := s.resource.UploadImage(ctx, model.UploadImageRequest{
-		Name:   header.Filename,
-		Reader: file,
+		Name:   storagerepo.NewObjectKey(ownerID, transform.Extension(attributes.Format)),
+		Reader: bytes.NewReader(data),
 	})
 	if err != nil {
-		return err
+		return model.ImageResponse{}, err
 	}
 
-	if _, err := s.imageRepo.SaveImage(ctx, model.Image{
-		URL: url,
-	}); err != nil {
-		return err
+	image := model.Image{
+		URL:              url,
+		OwnerID:          ownerID,
+		OriginalFilename: model.CleanFilename(header.Filename),
+		ImageAttributes:  attributes,
+	}
+	image.ID, err = s.imageRepo.SaveImage(ctx, image)
+	if err != nil {
+		return model.ImageResponse{}, err
 	}
 
-	return nil
+	return image.ToImageResponse(cfg), nil
 }
 
-func (s *ImageServImpl) GetAllImage(ctx context.Context, page int64, limit int64) (model.ImageResponses, *model.Meta, error) {
-	images, err := s.imageRepo.GetImages(ctx, page, limit)
+func (s *ImageServImpl) GetAllImage(ctx context.Context, ownerID int64, page int64, limit int64) (model.ImageResponses, *model.Meta, error) {
+	images, err := s.imageRepo.GetImages(ctx, ownerID, page, limit)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	total, err := s.imageRepo.CountImages(ctx)
+	total, err := s.imageRepo.CountImages(ctx, ownerID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,204 +116,246 @@ func (s *ImageServImpl) GetAllImage(ctx context.Context, page int64, limit int64
 	return model.Images(images).ToImageResponses(configs.GetConfig()), &meta, nil
 }
 
-func (s *ImageServImpl) GetImage(ctx context.Context, id int64) (model.ImageResponse, error) {
-	image, err := s.imageRepo.GetImage(ctx, id)
+func (s *ImageServImpl) GetImage(ctx context.Context, ownerID int64, id int64) (model.ImageResponse, error) {
+	image, err := s.imageRepo.GetImage(ctx, ownerID, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ImageResponse{}, httputils.ErrNotFound
+		}
 		return model.ImageResponse{}, err
 	}
 	return image.ToImageResponse(configs.GetConfig()), nil
 }
 
-func (s *ImageServImpl) TransformImage(ctx context.Context, id int64, req model.ImageTransformRequestOpts) (model.ImageResponse, error) {
-	requestedImage, err := s.imageRepo.GetImage(ctx, id)
+func (s *ImageServImpl) GetImageMetadata(ctx context.Context, ownerID int64, id int64) (model.ImageMetadataResponse, error) {
+	image, err := s.imageRepo.GetImage(ctx, ownerID, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ImageMetadataResponse{}, httputils.ErrNotFound
+		}
+		return model.ImageMetadataResponse{}, err
+	}
+	return image.ToImageMetadataResponse(configs.GetConfig()), nil
+}
+
+func (s *ImageServImpl) TransformImage(ctx context.Context, ownerID int64, id int64, req model.ImageTransformRequestOpts) (model.ImageResponse, error) {
+	requestedImage, err := s.imageRepo.GetImage(ctx, ownerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ImageResponse{}, httputils.ErrNotFound
+		}
 		return model.ImageResponse{}, err
 	}
 
-	imageData, err := s.resource.LoadImage(ctx, requestedImage)
+	newImage, _, err := s.transformer.Transform(ctx, requestedImage, req)
 	if err != nil {
 		return model.ImageResponse{}, err
 	}
-	transformed := false
-	if req.CropTransform != (model.CropTransformRequest{}) {
-		transformed = true
-		imageData.Image = CropImage(imageData.Image, req.CropTransform)
+	return newImage.ToImageResponse(configs.GetConfig()), nil
+}
+
+func (s *ImageServImpl) RenderImage(ctx context.Context, ownerID int64, id int64, req model.RenderRequest) (model.RenderedImage, error) {
+	if err := validateRender(configs.GetConfig(), req); err != nil {
+		return model.RenderedImage{}, err
+	}
+	opts := req.ToTransformRequest()
+	if err := s.transformer.Validate(opts); err != nil {
+		return model.RenderedImage{}, err
 	}
 
-	if req.Format != "" {
-		transformed = true
-		imageData.Image, err = ChangeImageFormat(imageData.Image, req.Format)
-		if err != nil {
-			return model.ImageResponse{}, err
+	src, err := s.imageRepo.GetImage(ctx, ownerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.RenderedImage{}, httputils.ErrNotFound
 		}
+		return model.RenderedImage{}, err
 	}
-	if req.Filters != (model.FilterTransformRequest{}) {
-		transformed = true
-		if req.Filters.Grayscale {
-			imageData.Image = GrayscaleFilterImage(imageData.Image)
-		}
-		if req.Filters.Sepia {
-			imageData.Image = SepiaFilterImage(imageData.Image)
-		}
+	hash, err := s.transformer.Hash(src, opts)
+	if err != nil {
+		return model.RenderedImage{}, err
 	}
 
-	if req.ResizeTransform != (model.ResizeTransformRequest{}) {
-		transformed = true
-		imageData.Image = ResizeImage(imageData.Image, req.ResizeTransform)
+	key := storagerepo.RenderKey(ownerID, hash)
+	data, err := s.resource.ReadObject(ctx, key)
+	if err == nil {
+		return model.RenderedImage{Data: data, Format: imageutils.Sniff(data), Hash: hash}, nil
 	}
-	if req.Rotate > 0 {
-		transformed = true
-		imageData.Image = RotateImage(imageData.Image, req.Rotate)
+	if !errors.Is(err, storagerepo.ErrObjectNotFound) {
+		return model.RenderedImage{}, err
 	}
 
-	if !transformed {
-		return requestedImage.ToImageResponse(configs.GetConfig()), nil
+	data, encoding, err := s.transformer.Render(ctx, src, opts)
+	if err != nil {
+		return model.RenderedImage{}, err
 	}
-	decoder, ok := getDecodeFunctions()[imageData.Format]
-	if !ok {
-		return model.ImageResponse{}, fmt.Errorf("decoder isn't implemented")
+	// concurrent renders of the same variant race to store it, the copy that
+	// loses is identical so the error is only logged
+	if _, err := s.resource.UploadImage(ctx, model.UploadImageRequest{Name: key, Reader: bytes.NewReader(data)}); err != nil {
+		log.Println("error when caching render: ", err)
 	}
+	return model.RenderedImage{Data: data, Format: encoding.Format, Hash: hash}, nil
+}
 
-	buf := bytes.Buffer{}
-	if err := decoder(&buf, imageData.Image); err != nil {
-		return model.ImageResponse{}, err
+func (s *ImageServImpl) SignImageURL(ctx context.Context, ownerID int64, id int64, req model.SignURLRequest) (model.SignedURLResponse, error) {
+	cfg := configs.GetConfig()
+	maxExpiresIn := int64(cfg.SIGNED_URL_MAX_TTL / time.Second)
+	// checked in seconds, larger values would overflow a time.Duration
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxExpiresIn {
+		return model.SignedURLResponse{}, fmt.Errorf("%w: expires_in must be between 1 and %d seconds", httputils.ErrBadRequest, maxExpiresIn)
 	}
-
-	uploadReq := model.UploadImageRequest{
-		Reader: &buf,
+	ttl := cfg.SIGNED_URL_DEFAULT_TTL
+	if req.ExpiresIn != 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
 	}
-	strSplit := strings.Split(requestedImage.GetObject(), ".")
-	fileName := strSplit[0]
-	fileExtentions := strSplit[1]
-	uploadReq.Name = fmt.Sprintf("%s:%s.%s", fileName, req.GenerateStr(), fileExtentions)
-	url, err := s.resource.UploadImage(ctx, uploadReq)
-	if err != nil {
-		return model.ImageResponse{}, err
+	if ttl <= 0 || ttl > cfg.SIGNED_URL_MAX_TTL {
+		return model.SignedURLResponse{}, fmt.Errorf("%w: expires_in must be between 1 and %d seconds", httputils.ErrBadRequest, maxExpiresIn)
 	}
 
-	newImage := model.Image{
-		URL: url,
-	}
-	savedId, err := s.imageRepo.SaveImage(ctx, newImage)
-	if err != nil {
-		return model.ImageResponse{}, err
+	query := url.Values{}
+	switch req.Action {
+	case model.SIGNED_RENDER:
+		if err := validateRender(cfg, req.Render); err != nil {
+			return model.SignedURLResponse{}, err
+		}
+		if err := s.transformer.Validate(req.Render.ToTransformRequest()); err != nil {
+			return model.SignedURLResponse{}, err
+		}
+		query = req.Render.Query()
+	case model.SIGNED_DOWNLOAD:
+	default:
+		return model.SignedURLResponse{}, fmt.Errorf("%w: action must be %s or %s", httputils.ErrBadRequest, model.SIGNED_RENDER, model.SIGNED_DOWNLOAD)
 	}
 
-	newImage.ID = savedId
-	// newImage := model.Image{}
-	return newImage.ToImageResponse(configs.GetConfig()), nil
-}
-
-func CropImage(imageData image.Image, cropReq model.CropTransformRequest) image.Image {
-	newImage := image.NewRGBA(imageData.Bounds())
-	draw.Draw(newImage, newImage.Bounds(), imageData, newImage.Rect.Min, draw.Src)
-
-	return newImage.SubImage(image.Rect(int(cropReq.X), int(cropReq.Y), int(cropReq.Width+cropReq.X), int(cropReq.Height+cropReq.Y)))
-}
-
-func RotateImage(imageData image.Image, rotateReq float64) image.Image {
-	return imaging.Rotate(imageData, rotateReq, color.Black)
-}
-
-func ResizeImage(imageData image.Image, resizeReq model.ResizeTransformRequest) image.Image {
-	// resize using nearest neighbour algorithm
-	// ref: https://medium.com/@chathuragunasekera/image-resampling-algorithms-for-pixel-manipulation-bee65dda1488
-	heightScale := float64(imageData.Bounds().Dy()) / float64(resizeReq.Height)
-	widthScale := float64(imageData.Bounds().Dx()) / float64(resizeReq.Width)
-
-	newImage := image.NewRGBA(image.Rect(0, 0, int(resizeReq.Width), int(resizeReq.Height)))
-	for y := 0; y < newImage.Bounds().Dy(); y++ {
-		for x := 0; x < newImage.Bounds().Dx(); x++ {
-			xCoords := x * int(widthScale)
-			yCoords := y * int(heightScale)
-
-			newImage.Set(x, y, imageData.At(xCoords, yCoords))
+	if _, err := s.imageRepo.GetImage(ctx, ownerID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.SignedURLResponse{}, httputils.ErrNotFound
 		}
+		return model.SignedURLResponse{}, err
 	}
 
-	return newImage
-}
-func ChangeImageFormat(imageData image.Image, targetFormat string) (image.Image, error) {
-	decoder, found := getDecodeFunctions()[targetFormat]
-	if !found {
-		return nil, fmt.Errorf("image decoder for %s not found", targetFormat)
-	}
-	var buf bytes.Buffer
-	if err := decoder(&buf, imageData); err != nil {
-		return nil, err
+	path := fmt.Sprintf("/signed/images/%d/%s", id, req.Action)
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	signed, err := signutils.Sign(cfg.SignedURLKey(), path, query, ownerID, expiresAt)
+	if err != nil {
+		return model.SignedURLResponse{}, err
 	}
+	return model.SignedURLResponse{
+		URL:       path + "?" + signed.Encode(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
 
-	newFormatImage, _, err := image.Decode(&buf)
+func (s *ImageServImpl) DownloadImage(ctx context.Context, ownerID int64, id int64) (model.Image, []byte, error) {
+	image, err := s.imageRepo.GetImage(ctx, ownerID, id)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Image{}, nil, httputils.ErrNotFound
+		}
+		return model.Image{}, nil, err
 	}
 
-	return newFormatImage, nil
-}
-func GrayscaleFilterImage(imageData image.Image) image.Image {
-	newImage := image.NewRGBA(imageData.Bounds())
-	for y := 0; y < imageData.Bounds().Dy(); y++ {
-		for x := 0; x < imageData.Bounds().Dx(); x++ {
-			r, g, b, a := imageData.At(x, y).RGBA()
-			// ref: https://www.johndcook.com/blog/2009/08/24/algorithms-convert-color-grayscale/
-			grayVal := 0.21*float64(r) + 0.72*float64(g) + 0.07*float64(b)
-			newImage.SetRGBA64(x, y, color.RGBA64{uint16(grayVal), uint16(grayVal), uint16(grayVal), uint16(a)})
-		}
+	data, err := s.resource.ReadObject(ctx, image.GetObject())
+	if err != nil {
+		return model.Image{}, nil, err
 	}
-	return newImage
+	if image.Format == "" {
+		// images stored before their attributes were recorded
+		image.Format = imageutils.Sniff(data)
+	}
+	return image, data, nil
 }
 
-func SepiaFilterImage(imageData image.Image) image.Image {
-	newImage := image.NewRGBA(imageData.Bounds())
-	for y := 0; y < imageData.Bounds().Dy(); y++ {
-		for x := 0; x < imageData.Bounds().Dx(); x++ {
-			r, g, b, a := imageData.At(x, y).RGBA()
-			tr := 0.393*float64(r) + 0.769*float64(g) + 0.189*float64(b)
-			tg := 0.349*float64(r) + 0.686*float64(g) + 0.168*float64(b)
-			tb := 0.272*float64(r) + 0.534*float64(g) + 0.131*float64(b)
-			newRGB := color.RGBA64{A: uint16(a)}
-			rMax, gMax, bMax, _ := color.White.RGBA()
-			if tr > float64(rMax) {
-				newRGB.R = uint16(r)
-			} else {
-				newRGB.R = uint16(tr)
-			}
-			if tg > float64(gMax) {
-				newRGB.G = uint16(g)
-			} else {
-				newRGB.G = uint16(tg)
-			}
-			if tb > float64(bMax) {
-				newRGB.B = uint16(b)
-			} else {
-				newRGB.B = uint16(tb)
-			}
-
-			newImage.SetRGBA64(x, y, newRGB)
+func (s *ImageServImpl) TransformImageBroker(ctx context.Context, ownerID int64, id int64, req model.ImageTransformRequestOpts) (model.TransformJobResponse, error) {
+	if err := s.transformer.Validate(req); err != nil {
+		return model.TransformJobResponse{}, err
+	}
+	if _, err := s.imageRepo.GetImage(ctx, ownerID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TransformJobResponse{}, httputils.ErrNotFound
 		}
+		return model.TransformJobResponse{}, err
 	}
-	return newImage
-}
-func getDecodeFunctions() map[string]imageConvertFunc {
-	return map[string]imageConvertFunc{
-		IMG_JPEG: func(w io.Writer, image image.Image) error {
-			return jpeg.Encode(w, image, nil)
-		},
-		IMG_PNG: png.Encode,
+
+	job, err := s.jobRepo.CreateJob(ctx, model.TransformJob{
+		ImageID: id,
+		OwnerID: ownerID,
+		Status:  model.JOB_QUEUED,
+	})
+	if err != nil {
+		return model.TransformJobResponse{}, err
 	}
-}
 
-func (s *ImageServImpl) TransformImageBroker(ctx context.Context, id int64, req model.ImageTransformRequestOpts) error {
 	data, err := json.Marshal(model.ImageTransformBrokerRequest{
 		ImageID: id,
+		OwnerID: ownerID,
+		JobID:   job.ID,
 		Req:     req,
 	})
 	if err != nil {
-		return err
+		return model.TransformJobResponse{}, err
 	}
 	err = s.producer.PublishCtx(ctx, configs.GetConfig().QUEUE_NAME, data)
 	if err != nil {
-		return err
+		job.Status = model.JOB_FAILED
+		job.Error = err.Error()
+		if updateErr := s.jobRepo.UpdateJob(ctx, job); updateErr != nil {
+			log.Println("error when updating job: ", updateErr)
+		}
+		return model.TransformJobResponse{}, err
+	}
+	return job.ToTransformJobResponse(), nil
+}
+
+// validateUpload checks the uploaded file is an allowed image within the
+// configured limits. Only the image header, and the frame count of GIFs, is
+// read so oversized images are rejected before their pixels are allocated.
+func validateUpload(cfg *configs.Config, data []byte) error {
+	if int64(len(data)) > cfg.UPLOAD_MAX_BYTES {
+		return fmt.Errorf("%w: images can't be larger than %d bytes", httputils.ErrPayloadTooLarge, cfg.UPLOAD_MAX_BYTES)
+	}
+
+	format := imageutils.Sniff(data)
+	if format == "" {
+		return fmt.Errorf("%w: the file is not a supported image", httputils.ErrUnsupportedMedia)
+	}
+	if !slices.Contains(cfg.UPLOAD_ALLOWED_FORMATS, format) {
+		return fmt.Errorf("%w: %s images are not allowed", httputils.ErrUnsupportedMedia, format)
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: invalid image: %v", httputils.ErrBadRequest, err)
+	}
+	if int64(imageConfig.Width) > cfg.UPLOAD_MAX_WIDTH || int64(imageConfig.Height) > cfg.UPLOAD_MAX_HEIGHT {
+		return fmt.Errorf("%w: images can't be larger than %dx%d pixels", httputils.ErrPayloadTooLarge, cfg.UPLOAD_MAX_WIDTH, cfg.UPLOAD_MAX_HEIGHT)
+	}
+	if int64(imageConfig.Width)*int64(imageConfig.Height) > cfg.UPLOAD_MAX_PIXELS {
+		return fmt.Errorf("%w: images can't have more than %d pixels", httputils.ErrPayloadTooLarge, cfg.UPLOAD_MAX_PIXELS)
+	}
+	if format == "gif" {
+		// every frame is decoded onto a full canvas
+		frames, err := imageutils.GIFFrameCount(data)
+		if err != nil {
+			return fmt.Errorf("%w: invalid image: %v", httputils.ErrBadRequest, err)
+		}
+		if err := imageutils.CheckAnimationSize(frames, imageConfig); err != nil {
+			return fmt.Errorf("%w: %v", httputils.ErrPayloadTooLarge, err)
+		}
+	}
+	return nil
+}
+
+// validateRender rejects renders larger than the images accepted on upload
+// and formats that can't be encoded.
+func validateRender(cfg *configs.Config, req model.RenderRequest) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", httputils.ErrBadRequest, err)
+	}
+	if req.Width > cfg.UPLOAD_MAX_WIDTH || req.Height > cfg.UPLOAD_MAX_HEIGHT {
+		return fmt.Errorf("%w: renders can't be larger than %dx%d pixels", httputils.ErrBadRequest, cfg.UPLOAD_MAX_WIDTH, cfg.UPLOAD_MAX_HEIGHT)
+	}
+	if req.Format != "" && req.Format != "jpg" && !transform.SupportsFormat(req.Format) {
+		return fmt.Errorf("%w: %s output isn't supported", httputils.ErrBadRequest, req.Format)
 	}
 	return nil
 }
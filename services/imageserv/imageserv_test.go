@@ -0,0 +1,62 @@
+package imageserv_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"mime/multipart"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/services/imageserv"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type uploadFile struct {
+	*bytes.Reader
+}
+
+func (uploadFile) Close() error { return nil }
+
+func TestUploadImageGIFBomb(t *testing.T) {
+	if err := configs.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 40 1x1 frames on a 4000x4000 canvas pass the header check alone
+	g := &gif.GIF{Config: image.Config{Width: 4000, Height: 4000}}
+	for i := 0; i < 40; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is rejected before any repository is used
+	serv := imageserv.New(nil, nil, nil, nil, nil)
+	var file multipart.File = uploadFile{bytes.NewReader(buf.Bytes())}
+	_, err := serv.UploadImage(context.Background(), 1, file, &multipart.FileHeader{Filename: "bomb.gif"})
+	if !errors.Is(err, httputils.ErrPayloadTooLarge) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrPayloadTooLarge, err)
+	}
+}
+
+func TestSignImageURLExpiresInOverflow(t *testing.T) {
+	if err := configs.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the seconds overflow a time.Duration and wrap around to under a second
+	serv := imageserv.New(nil, nil, nil, nil, nil)
+	req := model.SignURLRequest{Action: model.SIGNED_DOWNLOAD, ExpiresIn: 18_446_744_074}
+	_, err := serv.SignImageURL(context.Background(), 1, 1, req)
+	if !errors.Is(err, httputils.ErrBadRequest) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrBadRequest, err)
+	}
+}
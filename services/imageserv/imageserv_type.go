@@ -8,9 +8,17 @@ import (
 )
 
 type ImageServ interface {
-	UploadImage(ctx context.Context, file multipart.File, header *multipart.FileHeader) error
-	GetAllImage(ctx context.Context, page int64, limit int64) (model.ImageResponses, *model.Meta, error)
-	GetImage(ctx context.Context, id int64) (model.ImageResponse, error)
-	TransformImage(ctx context.Context, id int64, req model.ImageTransformRequestOpts) (model.ImageResponse, error)
-	TransformImageBroker(ctx context.Context, id int64, req model.ImageTransformRequestOpts) error
+	UploadImage(ctx context.Context, ownerID int64, file multipart.File, header *multipart.FileHeader) (model.ImageResponse, error)
+	GetAllImage(ctx context.Context, ownerID int64, page int64, limit int64) (model.ImageResponses, *model.Meta, error)
+	GetImage(ctx context.Context, ownerID int64, id int64) (model.ImageResponse, error)
+	GetImageMetadata(ctx context.Context, ownerID int64, id int64) (model.ImageMetadataResponse, error)
+	TransformImage(ctx context.Context, ownerID int64, id int64, req model.ImageTransformRequestOpts) (model.ImageResponse, error)
+	// RenderImage runs req synchronously, rendered variants are cached in the
+	// storage under their hash.
+	RenderImage(ctx context.Context, ownerID int64, id int64, req model.RenderRequest) (model.RenderedImage, error)
+	// SignImageURL returns a url giving access to a render or the download of
+	// the image without a token, until it expires.
+	SignImageURL(ctx context.Context, ownerID int64, id int64, req model.SignURLRequest) (model.SignedURLResponse, error)
+	DownloadImage(ctx context.Context, ownerID int64, id int64) (model.Image, []byte, error)
+	TransformImageBroker(ctx context.Context, ownerID int64, id int64, req model.ImageTransformRequestOpts) (model.TransformJobResponse, error)
 }
@@ -0,0 +1,48 @@
+package jobserv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/imagerepo"
+	"github.com/ARF-DEV/image-processing-api/repos/jobrepo"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+)
+
+type JobServImpl struct {
+	jobRepo   jobrepo.JobRepo
+	imageRepo imagerepo.ImageRepo
+}
+
+func New(jobRepo jobrepo.JobRepo, imageRepo imagerepo.ImageRepo) JobServ {
+	return &JobServImpl{
+		jobRepo:   jobRepo,
+		imageRepo: imageRepo,
+	}
+}
+
+func (s *JobServImpl) GetJob(ctx context.Context, ownerID int64, id int64) (model.TransformJobResponse, error) {
+	job, err := s.jobRepo.GetJob(ctx, ownerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TransformJobResponse{}, httputils.ErrNotFound
+		}
+		return model.TransformJobResponse{}, err
+	}
+
+	res := job.ToTransformJobResponse()
+	if job.Status != model.JOB_SUCCEEDED || job.ResultImageID == 0 {
+		return res, nil
+	}
+
+	image, err := s.imageRepo.GetImage(ctx, ownerID, job.ResultImageID)
+	if err != nil {
+		return model.TransformJobResponse{}, err
+	}
+	imageRes := image.ToImageResponse(configs.GetConfig())
+	res.Result = &imageRes
+	return res, nil
+}
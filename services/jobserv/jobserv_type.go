@@ -0,0 +1,11 @@
+package jobserv
+
+import (
+	"context"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+type JobServ interface {
+	GetJob(ctx context.Context, ownerID int64, id int64) (model.TransformJobResponse, error)
+}
@@ -8,20 +8,24 @@ import (
 	"log"
 	"time"
 
+	"github.com/ARF-DEV/image-processing-api/configs"
 	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/tokenrepo"
 	"github.com/ARF-DEV/image-processing-api/repos/userrepo"
 	"github.com/ARF-DEV/image-processing-api/utils/httputils"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/spf13/viper"
+	"github.com/ARF-DEV/image-processing-api/utils/jwtutils"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserServImpl struct {
-	userRepo userrepo.UserRepo
+	userRepo    userrepo.UserRepo
+	tokenRepo   tokenrepo.TokenRepo
+	tokenConfig jwtutils.TokenConfig
 }
 
-func New(userRepo userrepo.UserRepo) UserServ {
-	return &UserServImpl{userRepo: userRepo}
+func New(userRepo userrepo.UserRepo, tokenRepo tokenrepo.TokenRepo, tokenConfig jwtutils.TokenConfig) UserServ {
+	return &UserServImpl{userRepo: userRepo, tokenRepo: tokenRepo, tokenConfig: tokenConfig}
 }
 
 func (s *UserServImpl) Login(ctx context.Context, user model.User) (model.AutheticationResponse, error) {
@@ -36,17 +40,104 @@ func (s *UserServImpl) Login(ctx context.Context, user model.User) (model.Authet
 		log.Println("error when comparing password: ", err)
 		return model.AutheticationResponse{}, httputils.ErrUnauthorized
 	}
-	claims := &jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+	if userSrc.Disabled {
+		return model.AutheticationResponse{}, fmt.Errorf("%w: account disabled", httputils.ErrForbidden)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(viper.GetString("SECRET_KEY")))
+	// every login starts a session, refreshing keeps it
+	sessionID := uuid.NewString()
+	refreshToken, err := jwtutils.NewRefreshToken()
+	if err != nil {
+		return model.AutheticationResponse{}, err
+	}
+	err = s.tokenRepo.CreateRefreshToken(ctx, model.RefreshToken{
+		UserID:    userSrc.ID,
+		SessionID: sessionID,
+		TokenHash: jwtutils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(configs.GetConfig().REFRESH_TOKEN_TTL),
+	})
+	if err != nil {
+		return model.AutheticationResponse{}, err
+	}
+	return s.newAuthenticationResponse(userSrc, sessionID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair,
+// the refresh token can't be used again. Using a token that was already
+// exchanged revokes its whole session, as either the client or an attacker
+// holds a stolen copy.
+func (s *UserServImpl) Refresh(ctx context.Context, refreshToken string) (model.AutheticationResponse, error) {
+	stored, err := s.tokenRepo.GetRefreshToken(ctx, jwtutils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AutheticationResponse{}, fmt.Errorf("%w: invalid refresh token", httputils.ErrUnauthorized)
+		}
+		return model.AutheticationResponse{}, err
+	}
+	if stored.RevokedAt != nil {
+		if err := s.tokenRepo.RevokeSession(ctx, stored.SessionID); err != nil {
+			return model.AutheticationResponse{}, err
+		}
+		return model.AutheticationResponse{}, httputils.ErrTokenRevoked
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		return model.AutheticationResponse{}, httputils.ErrRefreshTokenExpired
+	}
+	// the role and status may have changed since the login
+	user, err := s.userRepo.GetUser(ctx, stored.UserID)
+	if err != nil {
+		return model.AutheticationResponse{}, err
+	}
+	if user.Disabled {
+		return model.AutheticationResponse{}, fmt.Errorf("%w: account disabled", httputils.ErrForbidden)
+	}
+
+	next, err := jwtutils.NewRefreshToken()
+	if err != nil {
+		return model.AutheticationResponse{}, err
+	}
+	err = s.tokenRepo.RotateRefreshToken(ctx, stored.ID, model.RefreshToken{
+		UserID:    stored.UserID,
+		SessionID: stored.SessionID,
+		TokenHash: jwtutils.HashToken(next),
+		ExpiresAt: time.Now().Add(configs.GetConfig().REFRESH_TOKEN_TTL),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// rotated by a concurrent refresh with the same token
+			if err := s.tokenRepo.RevokeSession(ctx, stored.SessionID); err != nil {
+				return model.AutheticationResponse{}, err
+			}
+			return model.AutheticationResponse{}, httputils.ErrTokenRevoked
+		}
+		return model.AutheticationResponse{}, err
+	}
+	return s.newAuthenticationResponse(user, stored.SessionID, next)
+}
+
+// Logout revokes the access token of principal and the refresh tokens of its
+// session.
+func (s *UserServImpl) Logout(ctx context.Context, principal model.Principal) error {
+	if err := s.tokenRepo.RevokeAccessToken(ctx, principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
+	}
+	if principal.SessionID == "" {
+		return nil
+	}
+	return s.tokenRepo.RevokeSession(ctx, principal.SessionID)
+}
+
+func (s *UserServImpl) newAuthenticationResponse(user model.User, sessionID string, refreshToken string) (model.AutheticationResponse, error) {
+	cfg := configs.GetConfig()
+	accessToken, _, err := jwtutils.NewAccessToken(s.tokenConfig, user.ID, user.Role, sessionID, cfg.ACCESS_TOKEN_TTL)
 	if err != nil {
 		return model.AutheticationResponse{}, err
 	}
 	return model.AutheticationResponse{
-		AccessToken: tokenStr,
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(cfg.ACCESS_TOKEN_TTL.Seconds()),
+		RefreshToken: refreshToken,
 	}, nil
 }
 
@@ -9,4 +9,6 @@ import (
 type UserServ interface {
 	Login(ctx context.Context, user model.User) (model.AutheticationResponse, error)
 	Register(ctx context.Context, user model.User) error
+	Refresh(ctx context.Context, refreshToken string) (model.AutheticationResponse, error)
+	Logout(ctx context.Context, principal model.Principal) error
 }
@@ -0,0 +1,235 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+const (
+	IMG_JPEG string = "jpeg"
+	IMG_PNG  string = "png"
+	IMG_GIF  string = "gif"
+	IMG_BMP  string = "bmp"
+	IMG_TIFF string = "tiff"
+	IMG_WEBP string = "webp"
+)
+
+type imageConvertFunc func(w io.Writer, image image.Image, opts model.CompressTransformRequest) (model.EncodingResult, error)
+
+var pngCompressionLevels = map[string]png.CompressionLevel{
+	model.COMPRESSION_DEFAULT: png.DefaultCompression,
+	model.COMPRESSION_NONE:    png.NoCompression,
+	model.COMPRESSION_SPEED:   png.BestSpeed,
+	model.COMPRESSION_BEST:    png.BestCompression,
+}
+
+var encoders = map[string]imageConvertFunc{
+	IMG_JPEG: func(w io.Writer, image image.Image, opts model.CompressTransformRequest) (model.EncodingResult, error) {
+		quality := opts.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return model.EncodingResult{Format: IMG_JPEG, Quality: quality}, jpeg.Encode(w, image, &jpeg.Options{Quality: int(quality)})
+	},
+	IMG_PNG: func(w io.Writer, image image.Image, opts model.CompressTransformRequest) (model.EncodingResult, error) {
+		level := opts.CompressionLevel
+		if level == "" {
+			level = model.COMPRESSION_DEFAULT
+		}
+		encoder := png.Encoder{CompressionLevel: pngCompressionLevels[level]}
+		return model.EncodingResult{Format: IMG_PNG, CompressionLevel: level}, encoder.Encode(w, image)
+	},
+	IMG_GIF: func(w io.Writer, image image.Image, opts model.CompressTransformRequest) (model.EncodingResult, error) {
+		return model.EncodingResult{Format: IMG_GIF}, gif.Encode(w, image, nil)
+	},
+	IMG_BMP: func(w io.Writer, image image.Image, opts model.CompressTransformRequest) (model.EncodingResult, error) {
+		return model.EncodingResult{Format: IMG_BMP}, bmp.Encode(w, image)
+	},
+	IMG_TIFF: func(w io.Writer, image image.Image, opts model.CompressTransformRequest) (model.EncodingResult, error) {
+		// tiff only knows uncompressed and deflate
+		level := opts.CompressionLevel
+		if level == "" {
+			level = model.COMPRESSION_DEFAULT
+		}
+		compression := tiff.Deflate
+		if level == model.COMPRESSION_NONE {
+			compression = tiff.Uncompressed
+		}
+		return model.EncodingResult{Format: IMG_TIFF, CompressionLevel: level}, tiff.Encode(w, image, &tiff.Options{Compression: compression})
+	},
+}
+
+// FALLBACK_FORMAT is used for results whose source format can only be
+// decoded, such as webp.
+const FALLBACK_FORMAT string = IMG_PNG
+
+var extensions = map[string]string{
+	IMG_JPEG: "jpg",
+	IMG_PNG:  "png",
+	IMG_GIF:  "gif",
+	IMG_BMP:  "bmp",
+	IMG_TIFF: "tiff",
+}
+
+// Encode writes info.Image encoded in info.Format with the settings in
+// info.Encoding and reports the settings that were used. Animations are only
+// kept by GIF, other formats get the first frame. Metadata is only kept by
+// JPEG when info.KeepMetadata is set.
+func Encode(w io.Writer, info model.ImageInfo) (model.EncodingResult, error) {
+	encoder, found := encoders[info.Format]
+	if !found {
+		return model.EncodingResult{}, fmt.Errorf("image encoder for %s not found", info.Format)
+	}
+	if info.Animation != nil && info.Format == IMG_GIF {
+		encoder = func(w io.Writer, _ image.Image, _ model.CompressTransformRequest) (model.EncodingResult, error) {
+			return model.EncodingResult{Format: IMG_GIF}, encodeAnimation(w, info.Animation)
+		}
+	}
+
+	var metadata *model.ImageMetadata
+	if info.KeepMetadata && info.Format == IMG_JPEG {
+		metadata = info.Metadata
+	}
+	opts := info.Encoding
+	if metadata != nil && opts.TargetSize > 0 {
+		// the metadata counts in the target size
+		metadataSize := int64(len(imageutils.EmbedJPEGMetadata([]byte{0xFF, 0xD8}, metadata)) - 2)
+		opts.TargetSize = max(1, opts.TargetSize-metadataSize)
+	}
+
+	var (
+		buf    *bytes.Buffer
+		result model.EncodingResult
+		err    error
+	)
+	if opts.TargetSize > 0 {
+		buf, result, err = encodeToTarget(info.Format, encoder, info.Image, opts)
+	} else {
+		buf, result, err = encodeBuffer(encoder, info.Image, opts)
+	}
+	if err != nil {
+		return model.EncodingResult{}, err
+	}
+	if metadata != nil {
+		buf = bytes.NewBuffer(imageutils.EmbedJPEGMetadata(buf.Bytes(), metadata))
+		result.Size = int64(buf.Len())
+		if result.TargetMet != nil {
+			targetMet := result.Size <= info.Encoding.TargetSize
+			result.TargetMet = &targetMet
+		}
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return model.EncodingResult{}, err
+	}
+	return result, nil
+}
+
+func encodeBuffer(encoder imageConvertFunc, img image.Image, opts model.CompressTransformRequest) (*bytes.Buffer, model.EncodingResult, error) {
+	buf := bytes.Buffer{}
+	result, err := encoder(&buf, img, opts)
+	if err != nil {
+		return nil, model.EncodingResult{}, err
+	}
+	result.Size = int64(buf.Len())
+	return &buf, result, nil
+}
+
+// encodeToTarget looks for the best encoding that fits in opts.TargetSize.
+// For JPEG it binary searches the highest quality up to opts.Quality (or
+// 100), for other formats it falls back to the strongest compression. When
+// nothing fits, the smallest attempt is kept.
+func encodeToTarget(format string, encoder imageConvertFunc, img image.Image, opts model.CompressTransformRequest) (*bytes.Buffer, model.EncodingResult, error) {
+	var (
+		buf    *bytes.Buffer
+		result model.EncodingResult
+		err    error
+	)
+
+	if format == IMG_JPEG {
+		low, high := int64(1), opts.Quality
+		if high == 0 {
+			high = 100
+		}
+		for low <= high {
+			mid := (low + high) / 2
+			attempt := opts
+			attempt.Quality = mid
+			attemptBuf, attemptResult, err := encodeBuffer(encoder, img, attempt)
+			if err != nil {
+				return nil, model.EncodingResult{}, err
+			}
+			if attemptResult.Size <= opts.TargetSize {
+				buf, result = attemptBuf, attemptResult
+				low = mid + 1
+			} else {
+				high = mid - 1
+			}
+		}
+		if buf == nil {
+			attempt := opts
+			attempt.Quality = 1
+			buf, result, err = encodeBuffer(encoder, img, attempt)
+		}
+	} else {
+		buf, result, err = encodeBuffer(encoder, img, opts)
+		if err == nil && result.Size > opts.TargetSize && opts.CompressionLevel != model.COMPRESSION_BEST {
+			attempt := opts
+			attempt.CompressionLevel = model.COMPRESSION_BEST
+			buf, result, err = encodeBuffer(encoder, img, attempt)
+		}
+	}
+	if err != nil {
+		return nil, model.EncodingResult{}, err
+	}
+
+	targetMet := result.Size <= opts.TargetSize
+	result.TargetMet = &targetMet
+	return buf, result, nil
+}
+
+// SupportsFormat reports whether images can be encoded in format.
+func SupportsFormat(format string) bool {
+	_, found := encoders[format]
+	return found
+}
+
+// Extension returns the file extension used for objects encoded in format.
+func Extension(format string) string {
+	if ext, found := extensions[format]; found {
+		return ext
+	}
+	return format
+}
+
+// gifPalette is used to quantize animation frames, it keeps a transparent
+// entry for frames that are not fully opaque.
+var gifPalette = append(color.Palette{color.Transparent}, palette.WebSafe...)
+
+func encodeAnimation(w io.Writer, animation *model.Animation) error {
+	g := gif.GIF{
+		Image:     make([]*image.Paletted, len(animation.Frames)),
+		Delay:     animation.Delays,
+		Disposal:  animation.Disposals,
+		LoopCount: animation.LoopCount,
+	}
+	for i, frame := range animation.Frames {
+		bounds := frame.Bounds()
+		paletted := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), gifPalette)
+		draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), frame, bounds.Min)
+		g.Image[i] = paletted
+	}
+	return gif.EncodeAll(w, &g)
+}
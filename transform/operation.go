@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+type validator interface {
+	Validate() error
+}
+
+// normalizer is implemented by operations that can give a canonical form of
+// their params, see TransformerImpl.Hash.
+type normalizer interface {
+	Normalize(params json.RawMessage) (json.RawMessage, error)
+}
+
+type operationFunc[T any] func(ctx context.Context, info *model.ImageInfo, params T) error
+
+// NewOperation builds an Operation whose params are decoded into T. T is
+// validated first when it implements Validate() error.
+func NewOperation[T any](apply func(ctx context.Context, info *model.ImageInfo, params T) error) Operation {
+	return operationFunc[T](apply)
+}
+
+func (f operationFunc[T]) decode(raw json.RawMessage) (T, error) {
+	var params T
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return params, err
+		}
+	}
+	if v, ok := any(params).(validator); ok {
+		if err := v.Validate(); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+// Normalize returns the params re-encoded from T, so equivalent params such
+// as omitted and zero fields give the same bytes.
+func (f operationFunc[T]) Normalize(raw json.RawMessage) (json.RawMessage, error) {
+	params, err := f.decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(params)
+}
+
+func (f operationFunc[T]) Validate(raw json.RawMessage) error {
+	_, err := f.decode(raw)
+	return err
+}
+
+func (f operationFunc[T]) Apply(ctx context.Context, info *model.ImageInfo, raw json.RawMessage) error {
+	params, err := f.decode(raw)
+	if err != nil {
+		return err
+	}
+	return f(ctx, info, params)
+}
+
+// animationOperation is implemented by operations that handle animations
+// themselves instead of being applied to each frame.
+type animationOperation interface {
+	handlesAnimation()
+}
+
+type animationOperationFunc[T any] struct {
+	operationFunc[T]
+}
+
+// NewAnimationOperation is like NewOperation, but apply receives the whole
+// animation in info.Animation instead of being called once per frame.
+func NewAnimationOperation[T any](apply func(ctx context.Context, info *model.ImageInfo, params T) error) Operation {
+	return animationOperationFunc[T]{operationFunc[T](apply)}
+}
+
+func (animationOperationFunc[T]) handlesAnimation() {}
@@ -0,0 +1,366 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/colorutils"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+	"github.com/disintegration/imaging"
+)
+
+func (t *TransformerImpl) registerDefaults() {
+	t.Register(model.OP_CROP, NewOperation(func(ctx context.Context, info *model.ImageInfo, req model.CropTransformRequest) error {
+		cropped, err := CropImage(info.Image, req)
+		if err != nil {
+			return err
+		}
+		info.Image = cropped
+		return nil
+	}))
+	t.Register(model.OP_FORMAT, NewOperation(func(ctx context.Context, info *model.ImageInfo, req model.FormatTransformRequest) error {
+		if !SupportsFormat(req.Format) {
+			return fmt.Errorf("image encoder for %s not found", req.Format)
+		}
+		info.Format = req.Format
+		return nil
+	}))
+	t.Register(model.OP_GRAYSCALE, NewOperation(func(ctx context.Context, info *model.ImageInfo, _ struct{}) error {
+		info.Image = GrayscaleFilterImage(info.Image)
+		return nil
+	}))
+	t.Register(model.OP_SEPIA, NewOperation(func(ctx context.Context, info *model.ImageInfo, _ struct{}) error {
+		info.Image = SepiaFilterImage(info.Image)
+		return nil
+	}))
+	t.Register(model.OP_RESIZE, NewOperation(func(ctx context.Context, info *model.ImageInfo, req model.ResizeTransformRequest) error {
+		var err error
+		info.Image, err = ResizeImage(info.Image, req)
+		return err
+	}))
+	t.Register(model.OP_ROTATE, NewOperation(func(ctx context.Context, info *model.ImageInfo, req model.RotateTransformRequest) error {
+		var background color.Color = color.Black
+		if req.Background != "" {
+			var err error
+			if background, err = colorutils.ParseColor(req.Background); err != nil {
+				return err
+			}
+		}
+		// every arbitrary angle grows the canvas, a pipeline of them would
+		// grow it without bound
+		if err := checkResultSize(rotatedSize(info.Image.Bounds(), req.Angle)); err != nil {
+			return err
+		}
+		info.Image = RotateImage(info.Image, req.Angle, background)
+		return nil
+	}))
+	t.Register(model.OP_FLIP, NewOperation(func(ctx context.Context, info *model.ImageInfo, req model.FlipTransformRequest) error {
+		info.Image = FlipImage(info.Image, req.Direction)
+		return nil
+	}))
+	t.Register(model.OP_TRANSPOSE, NewOperation(func(ctx context.Context, info *model.ImageInfo, _ struct{}) error {
+		info.Image = TransposeImage(info.Image)
+		return nil
+	}))
+	t.Register(model.OP_TRANSVERSE, NewOperation(func(ctx context.Context, info *model.ImageInfo, _ struct{}) error {
+		info.Image = TransverseImage(info.Image)
+		return nil
+	}))
+	t.Register(model.OP_WATERMARK, NewAnimationOperation(t.watermark))
+	t.Register(model.OP_COMPRESS, NewOperation(func(ctx context.Context, info *model.ImageInfo, req model.CompressTransformRequest) error {
+		info.Encoding = req
+		return nil
+	}))
+	t.Register(model.OP_AUTO_ORIENT, NewOperation(func(ctx context.Context, info *model.ImageInfo, _ struct{}) error {
+		AutoOrient(info)
+		return nil
+	}))
+	t.Register(model.OP_METADATA, NewAnimationOperation(func(ctx context.Context, info *model.ImageInfo, req model.MetadataTransformRequest) error {
+		info.KeepMetadata = req.Mode == model.METADATA_KEEP
+		return nil
+	}))
+	t.Register(model.OP_FRAME, NewAnimationOperation(func(ctx context.Context, info *model.ImageInfo, req model.FrameTransformRequest) error {
+		return ExtractFrame(info, int(req.Index))
+	}))
+}
+
+// AutoOrient turns the pixels upright following the EXIF orientation, the
+// orientation is then reset so kept metadata stays consistent.
+func AutoOrient(info *model.ImageInfo) {
+	if info.Metadata == nil {
+		return
+	}
+
+	switch info.Metadata.Orientation {
+	case 2:
+		info.Image = FlipImage(info.Image, model.FLIP_HORIZONTAL)
+	case 3:
+		info.Image = RotateImage(info.Image, 180, nil)
+	case 4:
+		info.Image = FlipImage(info.Image, model.FLIP_VERTICAL)
+	case 5:
+		info.Image = TransposeImage(info.Image)
+	case 6:
+		info.Image = RotateImage(info.Image, 270, nil)
+	case 7:
+		info.Image = TransverseImage(info.Image)
+	case 8:
+		info.Image = RotateImage(info.Image, 90, nil)
+	default:
+		return
+	}
+	imageutils.SetOrientation(info.Metadata, 1)
+}
+
+// ExtractFrame replaces an animation with one of its frames, a still image
+// only has the frame 0.
+func ExtractFrame(info *model.ImageInfo, index int) error {
+	if info.Animation == nil {
+		if index != 0 {
+			return fmt.Errorf("%w: frame %d is out of range, the image is not animated", httputils.ErrBadRequest, index)
+		}
+		return nil
+	}
+	if index >= len(info.Animation.Frames) {
+		return fmt.Errorf("%w: frame %d is out of range, the image has %d frames", httputils.ErrBadRequest, index, len(info.Animation.Frames))
+	}
+	info.Image = info.Animation.Frames[index]
+	info.Animation = nil
+	return nil
+}
+
+// CropImage returns the requested region, relative to the image bounds, as a
+// new image starting at the origin. The region is clipped to the image, it
+// fails with httputils.ErrBadRequest when nothing of the image is left.
+func CropImage(imageData image.Image, cropReq model.CropTransformRequest) (image.Image, error) {
+	min := imageData.Bounds().Min
+	rect := image.Rect(int(cropReq.X), int(cropReq.Y), int(cropReq.Width+cropReq.X), int(cropReq.Height+cropReq.Y)).
+		Add(min).
+		Intersect(imageData.Bounds())
+	if rect.Empty() {
+		bounds := imageData.Bounds()
+		return nil, fmt.Errorf("%w: the crop region is outside of the %dx%d image", httputils.ErrBadRequest, bounds.Dx(), bounds.Dy())
+	}
+
+	newImage := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(newImage, newImage.Bounds(), imageData, rect.Min, draw.Src)
+	return newImage, nil
+}
+
+// RotateImage rotates counter-clockwise by angle degrees. Multiples of 90 are
+// exact pixel moves, any other angle is resampled onto background.
+func RotateImage(imageData image.Image, angle float64, background color.Color) image.Image {
+	angle = math.Mod(angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+
+	switch angle {
+	case 0:
+		return imageData
+	case 90:
+		return FlipImage(TransposeImage(imageData), model.FLIP_VERTICAL)
+	case 180:
+		return FlipImage(FlipImage(imageData, model.FLIP_HORIZONTAL), model.FLIP_VERTICAL)
+	case 270:
+		return FlipImage(TransposeImage(imageData), model.FLIP_HORIZONTAL)
+	}
+	return imaging.Rotate(imageData, angle, background)
+}
+
+// rotatedSize is the size of the canvas fitting bounds turned by angle
+// degrees, rounded up.
+func rotatedSize(bounds image.Rectangle, angle float64) (int, int) {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	return int(math.Ceil(w*cos + h*sin)), int(math.Ceil(w*sin + h*cos))
+}
+
+// FlipImage mirrors the image horizontally (left to right) or vertically
+// (top to bottom).
+func FlipImage(imageData image.Image, direction string) image.Image {
+	bounds := imageData.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	newImage := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			srcX, srcY := x, y
+			if direction == model.FLIP_HORIZONTAL {
+				srcX = w - 1 - x
+			} else {
+				srcY = h - 1 - y
+			}
+			newImage.Set(x, y, imageData.At(bounds.Min.X+srcX, bounds.Min.Y+srcY))
+		}
+	}
+	return newImage
+}
+
+// TransposeImage mirrors the image over its top-left to bottom-right diagonal.
+func TransposeImage(imageData image.Image) image.Image {
+	bounds := imageData.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	newImage := image.NewRGBA64(image.Rect(0, 0, h, w))
+	for y := 0; y < w; y++ {
+		for x := 0; x < h; x++ {
+			newImage.Set(x, y, imageData.At(bounds.Min.X+y, bounds.Min.Y+x))
+		}
+	}
+	return newImage
+}
+
+// TransverseImage mirrors the image over its top-right to bottom-left diagonal.
+func TransverseImage(imageData image.Image) image.Image {
+	bounds := imageData.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	newImage := image.NewRGBA64(image.Rect(0, 0, h, w))
+	for y := 0; y < w; y++ {
+		for x := 0; x < h; x++ {
+			newImage.Set(x, y, imageData.At(bounds.Min.X+w-1-y, bounds.Min.Y+h-1-x))
+		}
+	}
+	return newImage
+}
+
+var resampleFilters = map[string]imaging.ResampleFilter{
+	model.FILTER_NEAREST:  imaging.NearestNeighbor,
+	model.FILTER_BILINEAR: imaging.Linear,
+	model.FILTER_BICUBIC:  imaging.CatmullRom,
+	model.FILTER_LANCZOS:  imaging.Lanczos,
+}
+
+// ResizeImage scales the image following the request mode, see
+// model.ResizeTransformRequest.
+func ResizeImage(imageData image.Image, resizeReq model.ResizeTransformRequest) (image.Image, error) {
+	bounds := imageData.Bounds()
+	srcW, srcH := float64(bounds.Dx()), float64(bounds.Dy())
+	width, height := int(resizeReq.Width), int(resizeReq.Height)
+	gravity := resizeReq.Gravity
+	if gravity == "" {
+		gravity = model.POSITION_CENTER
+	}
+
+	// a single dimension keeps the aspect ratio whatever the mode
+	if width == 0 || height == 0 {
+		if width == 0 {
+			width = scaledSide(srcW, float64(height)/srcH)
+		} else {
+			height = scaledSide(srcH, float64(width)/srcW)
+		}
+		if err := checkResultSize(width, height); err != nil {
+			return nil, err
+		}
+		return resample(imageData, width, height, resizeReq.Filter), nil
+	}
+
+	switch resizeReq.Mode {
+	case model.RESIZE_FIT:
+		scale := math.Min(float64(width)/srcW, float64(height)/srcH)
+		return resample(imageData, scaledSide(srcW, scale), scaledSide(srcH, scale), resizeReq.Filter), nil
+	case model.RESIZE_FILL, model.RESIZE_COVER:
+		scale := math.Max(float64(width)/srcW, float64(height)/srcH)
+		// the overflow of narrow images can be much larger than the box
+		if err := checkResultSize(scaledSide(srcW, scale), scaledSide(srcH, scale)); err != nil {
+			return nil, err
+		}
+		scaled := resample(imageData, scaledSide(srcW, scale), scaledSide(srcH, scale), resizeReq.Filter)
+		origin := anchorPoint(scaled.Bounds(), image.Pt(width, height), gravity, 0)
+		return imaging.Crop(scaled, image.Rectangle{Min: origin, Max: origin.Add(image.Pt(width, height))}), nil
+	case model.RESIZE_PAD:
+		var background color.Color = color.Black
+		if resizeReq.Background != "" {
+			var err error
+			if background, err = colorutils.ParseColor(resizeReq.Background); err != nil {
+				return nil, err
+			}
+		}
+		scale := math.Min(float64(width)/srcW, float64(height)/srcH)
+		scaled := resample(imageData, scaledSide(srcW, scale), scaledSide(srcH, scale), resizeReq.Filter)
+		canvas := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+		origin := anchorPoint(canvas.Bounds(), scaled.Bounds().Size(), gravity, 0)
+		draw.Draw(canvas, scaled.Bounds().Add(origin), scaled, scaled.Bounds().Min, draw.Over)
+		return canvas, nil
+	}
+	return resample(imageData, width, height, resizeReq.Filter), nil
+}
+
+// checkResultSize rejects results larger than the images accepted on upload.
+func checkResultSize(width int, height int) error {
+	if err := model.CheckImageSize(int64(width), int64(height)); err != nil {
+		return fmt.Errorf("%w: %v", httputils.ErrBadRequest, err)
+	}
+	return nil
+}
+
+// scaledSide scales a side length, never going below one pixel.
+func scaledSide(side float64, scale float64) int {
+	return max(1, int(math.Round(side*scale)))
+}
+
+// resample scales the image to width x height with the named filter. Without
+// a filter, lanczos is used when the image shrinks and bicubic when it grows.
+func resample(imageData image.Image, width, height int, filterName string) *image.NRGBA {
+	bounds := imageData.Bounds()
+	if filterName == "" {
+		filterName = model.FILTER_LANCZOS
+		if width*height > bounds.Dx()*bounds.Dy() {
+			filterName = model.FILTER_BICUBIC
+		}
+	}
+	return imaging.Resize(imageData, width, height, resampleFilters[filterName])
+}
+
+func GrayscaleFilterImage(imageData image.Image) image.Image {
+	bounds := imageData.Bounds()
+	newImage := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := imageData.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// ref: https://www.johndcook.com/blog/2009/08/24/algorithms-convert-color-grayscale/
+			grayVal := 0.21*float64(r) + 0.72*float64(g) + 0.07*float64(b)
+			newImage.SetRGBA64(x, y, color.RGBA64{uint16(grayVal), uint16(grayVal), uint16(grayVal), uint16(a)})
+		}
+	}
+	return newImage
+}
+
+func SepiaFilterImage(imageData image.Image) image.Image {
+	bounds := imageData.Bounds()
+	newImage := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := imageData.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			tr := 0.393*float64(r) + 0.769*float64(g) + 0.189*float64(b)
+			tg := 0.349*float64(r) + 0.686*float64(g) + 0.168*float64(b)
+			tb := 0.272*float64(r) + 0.534*float64(g) + 0.131*float64(b)
+			newRGB := color.RGBA64{A: uint16(a)}
+			rMax, gMax, bMax, _ := color.White.RGBA()
+			if tr > float64(rMax) {
+				newRGB.R = uint16(r)
+			} else {
+				newRGB.R = uint16(tr)
+			}
+			if tg > float64(gMax) {
+				newRGB.G = uint16(g)
+			} else {
+				newRGB.G = uint16(tg)
+			}
+			if tb > float64(bMax) {
+				newRGB.B = uint16(b)
+			} else {
+				newRGB.B = uint16(tb)
+			}
+
+			newImage.SetRGBA64(x, y, newRGB)
+		}
+	}
+	return newImage
+}
@@ -0,0 +1,237 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/repos/imagerepo"
+	"github.com/ARF-DEV/image-processing-api/repos/storagerepo"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+)
+
+// MAX_STEPS bounds the length of a pipeline.
+const MAX_STEPS int = 20
+
+type ownerKey struct{}
+
+// WithOwner returns a context telling operations which user the transformed
+// image belongs to, so they only read that user's images.
+func WithOwner(ctx context.Context, ownerID int64) context.Context {
+	return context.WithValue(ctx, ownerKey{}, ownerID)
+}
+
+func OwnerFromContext(ctx context.Context) (int64, bool) {
+	ownerID, ok := ctx.Value(ownerKey{}).(int64)
+	return ownerID, ok
+}
+
+type TransformerImpl struct {
+	resource   storagerepo.StorageRepo
+	imageRepo  imagerepo.ImageRepo
+	operations map[string]Operation
+}
+
+func New(resource storagerepo.StorageRepo, imageRepo imagerepo.ImageRepo) Transformer {
+	t := &TransformerImpl{
+		resource:   resource,
+		imageRepo:  imageRepo,
+		operations: map[string]Operation{},
+	}
+	t.registerDefaults()
+	return t
+}
+
+func (t *TransformerImpl) Register(name string, op Operation) {
+	t.operations[name] = op
+}
+
+func (t *TransformerImpl) Validate(req model.ImageTransformRequestOpts) error {
+	if len(req.Pipeline) > 0 && req.HasLegacyOpts() {
+		return fmt.Errorf("%w: steps can't be combined with the other transformations", httputils.ErrBadRequest)
+	}
+
+	steps := req.Steps()
+	if len(steps) > MAX_STEPS {
+		return fmt.Errorf("%w: a pipeline can't have more than %d steps", httputils.ErrBadRequest, MAX_STEPS)
+	}
+	for i, step := range steps {
+		op, found := t.operations[step.Op]
+		if !found {
+			return fmt.Errorf("%w: step %d: unknown transformation %q", httputils.ErrBadRequest, i, step.Op)
+		}
+		if err := op.Validate(step.Params); err != nil {
+			return fmt.Errorf("%w: step %d: invalid %s params: %v", httputils.ErrBadRequest, i, step.Op, err)
+		}
+	}
+	return nil
+}
+
+func (t *TransformerImpl) Hash(src model.Image, req model.ImageTransformRequestOpts) (string, error) {
+	source := src.ContentHash
+	if source == "" {
+		// images stored before content hashes were recorded
+		source = fmt.Sprintf("image:%d", src.ID)
+	}
+
+	steps := req.Steps()
+	normalized := make([]model.TransformStep, len(steps))
+	for i, step := range steps {
+		op, found := t.operations[step.Op]
+		if !found {
+			return "", fmt.Errorf("%w: step %d: unknown transformation %q", httputils.ErrBadRequest, i, step.Op)
+		}
+		params := step.Params
+		if n, ok := op.(normalizer); ok {
+			var err error
+			if params, err = n.Normalize(step.Params); err != nil {
+				return "", fmt.Errorf("%w: step %d: invalid %s params: %v", httputils.ErrBadRequest, i, step.Op, err)
+			}
+		}
+		normalized[i] = model.TransformStep{Op: step.Op, Params: params}
+	}
+
+	spec, err := json.Marshal(struct {
+		Source string                `json:"source"`
+		Steps  []model.TransformStep `json:"steps"`
+	}{source, normalized})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(spec)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (t *TransformerImpl) Apply(ctx context.Context, info *model.ImageInfo, steps []model.TransformStep) error {
+	for _, step := range steps {
+		op, found := t.operations[step.Op]
+		if !found {
+			return fmt.Errorf("unknown transformation %q", step.Op)
+		}
+		if err := applyStep(ctx, op, info, step.Params); err != nil {
+			return fmt.Errorf("error when applying %s: %w", step.Op, err)
+		}
+	}
+	return nil
+}
+
+// applyStep applies op to every frame of an animation, the format and
+// encoding set while processing the first frame are kept for the whole
+// animation.
+func applyStep(ctx context.Context, op Operation, info *model.ImageInfo, params json.RawMessage) error {
+	if _, ok := op.(animationOperation); ok || info.Animation == nil {
+		return op.Apply(ctx, info, params)
+	}
+
+	for i, frame := range info.Animation.Frames {
+		frameInfo := model.ImageInfo{Image: frame, Format: info.Format, Encoding: info.Encoding}
+		if err := op.Apply(ctx, &frameInfo, params); err != nil {
+			return err
+		}
+		info.Animation.Frames[i] = frameInfo.Image
+		if i == 0 {
+			info.Format, info.Encoding = frameInfo.Format, frameInfo.Encoding
+		}
+	}
+	info.Image = info.Animation.Frames[0]
+	return nil
+}
+
+func (t *TransformerImpl) Transform(ctx context.Context, src model.Image, req model.ImageTransformRequestOpts) (model.Image, model.EncodingResult, error) {
+	if err := t.Validate(req); err != nil {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+	steps := req.Steps()
+	if len(steps) == 0 {
+		return src, model.EncodingResult{}, nil
+	}
+
+	hash, err := t.Hash(src, req)
+	if err != nil {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+	existing, err := t.imageRepo.FindImageByTransformHash(ctx, src.OwnerID, hash)
+	if err == nil {
+		return existing, model.EncodingResult{Format: existing.Format, Size: existing.ByteSize, Reused: true}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+
+	buf, encoding, format, err := t.render(ctx, src, steps)
+	if err != nil {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+	attributes, err := imageutils.Describe(buf.Bytes())
+	if err != nil {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+
+	url, err := t.resource.UploadImage(ctx, model.UploadImageRequest{
+		Reader: buf,
+		Name:   storagerepo.NewObjectKey(src.OwnerID, Extension(format)),
+	})
+	if err != nil {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+
+	// outputs are named after their source and the applied steps
+	fileName := strings.TrimSuffix(src.OriginalFilename, path.Ext(src.OriginalFilename))
+	if fileName == "" {
+		fileName = fmt.Sprintf("image-%d", src.ID)
+	}
+	newImage := model.Image{
+		URL:              url,
+		OwnerID:          src.OwnerID,
+		OriginalFilename: model.CleanFilename(fmt.Sprintf("%s:%s.%s", fileName, req.GenerateStr(), Extension(format))),
+		TransformHash:    hash,
+		ImageAttributes:  attributes,
+	}
+	newImage.ID, err = t.imageRepo.SaveImage(ctx, newImage)
+	if err != nil {
+		return model.Image{}, model.EncodingResult{}, err
+	}
+	return newImage, encoding, nil
+}
+
+func (t *TransformerImpl) Render(ctx context.Context, src model.Image, req model.ImageTransformRequestOpts) ([]byte, model.EncodingResult, error) {
+	if err := t.Validate(req); err != nil {
+		return nil, model.EncodingResult{}, err
+	}
+	buf, encoding, _, err := t.render(ctx, src, req.Steps())
+	if err != nil {
+		return nil, model.EncodingResult{}, err
+	}
+	return buf.Bytes(), encoding, nil
+}
+
+// render loads src and encodes the result of applying steps, returning the
+// format it was encoded in.
+func (t *TransformerImpl) render(ctx context.Context, src model.Image, steps []model.TransformStep) (*bytes.Buffer, model.EncodingResult, string, error) {
+	imageData, err := t.resource.LoadImage(ctx, src)
+	if err != nil {
+		return nil, model.EncodingResult{}, "", err
+	}
+	if !SupportsFormat(imageData.Format) {
+		imageData.Format = FALLBACK_FORMAT
+	}
+	if err := t.Apply(WithOwner(ctx, src.OwnerID), &imageData, steps); err != nil {
+		return nil, model.EncodingResult{}, "", err
+	}
+
+	buf := &bytes.Buffer{}
+	encoding, err := Encode(buf, imageData)
+	if err != nil {
+		return nil, model.EncodingResult{}, "", err
+	}
+	return buf, encoding, imageData.Format, nil
+}
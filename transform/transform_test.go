@@ -0,0 +1,503 @@
+package transform_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/transform"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+)
+
+func newTestImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 10), uint8(y * 10), 0, 255})
+		}
+	}
+	return img
+}
+
+func TestApply(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := model.ImageInfo{Image: newTestImage(10, 6), Format: transform.IMG_JPEG}
+
+	opts := model.ImageTransformRequestOpts{
+		CropTransform:   model.CropTransformRequest{X: 2, Y: 1, Width: 4, Height: 4},
+		ResizeTransform: model.ResizeTransformRequest{Width: 2, Height: 2},
+		Format:          transform.IMG_PNG,
+	}
+	if err := tr.Apply(context.Background(), &info, opts.Steps()); err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Image.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("error expected %v, but got %v", image.Rect(0, 0, 2, 2), info.Image.Bounds())
+	}
+	if info.Format != transform.IMG_PNG {
+		t.Fatalf("error expected %v, but got %v", transform.IMG_PNG, info.Format)
+	}
+	// the top left pixel averages the 2x2 block at (2, 1) of the source
+	if r, g, _, _ := info.Image.At(0, 0).RGBA(); r>>8 != 25 || g>>8 != 15 {
+		t.Fatalf("error expected (25, 15), but got (%v, %v)", r>>8, g>>8)
+	}
+}
+
+func TestApplyPipeline(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := model.ImageInfo{Image: newTestImage(8, 8), Format: transform.IMG_PNG}
+
+	// resize first, the crop then works on the resized image
+	opts := model.ImageTransformRequestOpts{
+		Pipeline: []model.TransformStep{
+			model.NewTransformStep(model.OP_RESIZE, model.ResizeTransformRequest{Width: 4, Height: 4}),
+			model.NewTransformStep(model.OP_CROP, model.CropTransformRequest{X: 1, Y: 1, Width: 3, Height: 3}),
+			model.NewTransformStep(model.OP_RESIZE, model.ResizeTransformRequest{Width: 6, Height: 3}),
+		},
+	}
+	if err := tr.Validate(opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Apply(context.Background(), &info, opts.Steps()); err != nil {
+		t.Fatal(err)
+	}
+	if info.Image.Bounds() != image.Rect(0, 0, 6, 3) {
+		t.Fatalf("error expected %v, but got %v", image.Rect(0, 0, 6, 3), info.Image.Bounds())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tr := transform.New(nil, nil)
+	tests := map[string]model.ImageTransformRequestOpts{
+		"unknown operation":   {Pipeline: []model.TransformStep{{Op: "blur"}}},
+		"invalid params":      {Pipeline: []model.TransformStep{{Op: model.OP_RESIZE, Params: []byte(`{"width": -1}`)}}},
+		"malformed params":    {Pipeline: []model.TransformStep{{Op: model.OP_CROP, Params: []byte(`[]`)}}},
+		"oversized resize":    {Pipeline: []model.TransformStep{{Op: model.OP_RESIZE, Params: []byte(`{"width": 1099511627776, "height": 1099511627776}`)}}},
+		"overflowing crop":    {Pipeline: []model.TransformStep{{Op: model.OP_CROP, Params: []byte(`{"x": 9223372036854775000, "y": 0, "width": 1000, "height": 1}`)}}},
+		"too many pixels":     {Pipeline: []model.TransformStep{{Op: model.OP_RESIZE, Params: []byte(`{"width": 12000, "height": 12000}`)}}},
+		"oversized watermark": {Pipeline: []model.TransformStep{{Op: model.OP_WATERMARK, Params: []byte(`{"text": "x", "size": 1e12}`)}}},
+		"steps and fields": {
+			Pipeline: []model.TransformStep{{Op: model.OP_GRAYSCALE}},
+			Rotate:   90,
+		},
+	}
+	for name, opts := range tests {
+		err := tr.Validate(opts)
+		if !errors.Is(err, httputils.ErrBadRequest) {
+			t.Fatalf("%s: error expected %v, but got %v", name, httputils.ErrBadRequest, err)
+		}
+	}
+}
+
+func TestApplyOversizedResize(t *testing.T) {
+	tr := transform.New(nil, nil)
+	// the height follows the aspect ratio of the narrow source
+	tests := map[string]model.ResizeTransformRequest{
+		"single dimension": {Width: 10000},
+		"fill":             {Width: 5000, Height: 5000, Mode: model.RESIZE_FILL},
+	}
+	for name, req := range tests {
+		info := model.ImageInfo{Image: newTestImage(1, 100), Format: transform.IMG_PNG}
+		steps := []model.TransformStep{model.NewTransformStep(model.OP_RESIZE, req)}
+		if err := tr.Apply(context.Background(), &info, steps); !errors.Is(err, httputils.ErrBadRequest) {
+			t.Fatalf("%s: error expected %v, but got %v", name, httputils.ErrBadRequest, err)
+		}
+	}
+}
+
+func TestApplyOversizedRotate(t *testing.T) {
+	tr := transform.New(nil, nil)
+	// fits, but not once turned 45 degrees
+	info := model.ImageInfo{Image: image.NewRGBA(image.Rect(0, 0, 10000, 2)), Format: transform.IMG_PNG}
+	steps := []model.TransformStep{model.NewTransformStep(model.OP_ROTATE, model.RotateTransformRequest{Angle: 45})}
+	if err := tr.Apply(context.Background(), &info, steps); !errors.Is(err, httputils.ErrBadRequest) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrBadRequest, err)
+	}
+}
+
+func TestApplyCropOutside(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := model.ImageInfo{Image: newTestImage(10, 6), Format: transform.IMG_PNG}
+	steps := []model.TransformStep{model.NewTransformStep(model.OP_CROP, model.CropTransformRequest{X: 20, Y: 0, Width: 5, Height: 5})}
+	if err := tr.Apply(context.Background(), &info, steps); !errors.Is(err, httputils.ErrBadRequest) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrBadRequest, err)
+	}
+}
+
+func TestApplyUnknownOperation(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := model.ImageInfo{Image: newTestImage(2, 2), Format: transform.IMG_PNG}
+
+	err := tr.Apply(context.Background(), &info, []model.TransformStep{{Op: "blur"}})
+	if err == nil {
+		t.Fatal("error expected unknown operation to fail")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tr := transform.New(nil, nil)
+	tr.Register("clear", transform.NewOperation(func(ctx context.Context, info *model.ImageInfo, _ struct{}) error {
+		info.Image = image.NewRGBA(info.Image.Bounds())
+		return nil
+	}))
+
+	info := model.ImageInfo{Image: newTestImage(3, 3), Format: transform.IMG_PNG}
+	if err := tr.Apply(context.Background(), &info, []model.TransformStep{{Op: "clear"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := info.Image.At(1, 1).RGBA(); a != 0 {
+		t.Fatalf("error expected %v, but got %v", 0, a)
+	}
+}
+
+func TestWatermarkText(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := model.ImageInfo{Image: image.NewRGBA(image.Rect(0, 0, 200, 80)), Format: transform.IMG_PNG}
+
+	step := model.NewTransformStep(model.OP_WATERMARK, model.WatermarkTransformRequest{
+		Text:     "sample",
+		Color:    "#ffffff",
+		Position: model.POSITION_TOP_LEFT,
+		Margin:   4,
+		Opacity:  1,
+	})
+	if err := tr.Apply(context.Background(), &info, []model.TransformStep{step}); err != nil {
+		t.Fatal(err)
+	}
+
+	marked := func(x0, y0, x1, y1 int) bool {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if r, _, _, _ := info.Image.At(x, y).RGBA(); r > 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !marked(0, 0, 100, 40) {
+		t.Fatal("error expected the text in the top left corner")
+	}
+	if marked(100, 40, 200, 80) {
+		t.Fatal("error expected the bottom right corner to be untouched")
+	}
+}
+
+func TestWatermarkEmptyTile(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := model.ImageInfo{Image: newTestImage(20, 20), Format: transform.IMG_PNG}
+	// the text is measured 0 pixels wide at this size
+	step := model.NewTransformStep(model.OP_WATERMARK, model.WatermarkTransformRequest{Text: "a", Size: 0.01, Tile: true})
+	if err := tr.Apply(context.Background(), &info, []model.TransformStep{step}); !errors.Is(err, httputils.ErrBadRequest) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrBadRequest, err)
+	}
+}
+
+func TestRotateImage(t *testing.T) {
+	src := newTestImage(3, 2)
+	tests := map[float64]struct{ x, y, srcX, srcY int }{
+		// counter-clockwise, the top right pixel ends up in the top left corner
+		90:   {0, 0, 2, 0},
+		-270: {0, 0, 2, 0},
+		180:  {0, 0, 2, 1},
+		270:  {0, 0, 0, 1},
+	}
+	for angle, tt := range tests {
+		rotated := transform.RotateImage(src, angle, color.Black)
+		if angle != 180 && rotated.Bounds() != image.Rect(0, 0, 2, 3) {
+			t.Fatalf("%v: error expected %v, but got %v", angle, image.Rect(0, 0, 2, 3), rotated.Bounds())
+		}
+		want := color.RGBA64Model.Convert(src.At(tt.srcX, tt.srcY))
+		if got := color.RGBA64Model.Convert(rotated.At(tt.x, tt.y)); got != want {
+			t.Fatalf("%v: error expected %v, but got %v", angle, want, got)
+		}
+	}
+}
+
+func TestFlipImage(t *testing.T) {
+	src := newTestImage(3, 2)
+	flipped := transform.FlipImage(src, model.FLIP_HORIZONTAL)
+	if got, want := color.RGBA64Model.Convert(flipped.At(0, 1)), color.RGBA64Model.Convert(src.At(2, 1)); got != want {
+		t.Fatalf("error expected %v, but got %v", want, got)
+	}
+	flipped = transform.FlipImage(src, model.FLIP_VERTICAL)
+	if got, want := color.RGBA64Model.Convert(flipped.At(2, 0)), color.RGBA64Model.Convert(src.At(2, 1)); got != want {
+		t.Fatalf("error expected %v, but got %v", want, got)
+	}
+}
+
+func TestEncodeTargetSize(t *testing.T) {
+	info := model.ImageInfo{Image: newTestImage(64, 64), Format: transform.IMG_JPEG}
+
+	full := bytes.Buffer{}
+	info.Encoding = model.CompressTransformRequest{Quality: 100}
+	fullResult, err := transform.Encode(&full, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fullResult.Quality != 100 || fullResult.Size != int64(full.Len()) {
+		t.Fatalf("error expected quality 100 and size %v, but got %+v", full.Len(), fullResult)
+	}
+
+	buf := bytes.Buffer{}
+	info.Encoding = model.CompressTransformRequest{TargetSize: fullResult.Size / 2}
+	result, err := transform.Encode(&buf, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TargetMet == nil || !*result.TargetMet {
+		t.Fatalf("error expected target to be met, but got %+v", result)
+	}
+	if int64(buf.Len()) > fullResult.Size/2 || result.Quality >= 100 {
+		t.Fatalf("error expected at most %v bytes below quality 100, but got %+v", fullResult.Size/2, result)
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	// alternating black and white columns
+	src := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x += 2 {
+			src.SetGray(x, y, color.Gray{255})
+		}
+	}
+
+	downscaled, err := transform.ResizeImage(src, model.ResizeTransformRequest{Width: 4, Height: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := downscaled.At(1, 1).RGBA(); r>>8 < 100 || r>>8 > 155 {
+		t.Fatalf("error expected a gray pixel, but got %v", r>>8)
+	}
+
+	nearest, err := transform.ResizeImage(src, model.ResizeTransformRequest{Width: 16, Height: 16, Filter: model.FILTER_NEAREST})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 16; x++ {
+		if r, _, _, _ := nearest.At(x, 0).RGBA(); r>>8 != 0 && r>>8 != 255 {
+			t.Fatalf("error expected black or white at %v, but got %v", x, r>>8)
+		}
+	}
+}
+
+func TestResizeModes(t *testing.T) {
+	src := newTestImage(20, 10)
+	tests := []struct {
+		req  model.ResizeTransformRequest
+		size image.Point
+	}{
+		{model.ResizeTransformRequest{Width: 10}, image.Pt(10, 5)},
+		{model.ResizeTransformRequest{Height: 5}, image.Pt(10, 5)},
+		{model.ResizeTransformRequest{Width: 8, Height: 8}, image.Pt(8, 8)},
+		{model.ResizeTransformRequest{Width: 8, Height: 8, Mode: model.RESIZE_FIT}, image.Pt(8, 4)},
+		{model.ResizeTransformRequest{Width: 8, Height: 8, Mode: model.RESIZE_FILL}, image.Pt(8, 8)},
+		{model.ResizeTransformRequest{Width: 8, Height: 8, Mode: model.RESIZE_PAD}, image.Pt(8, 8)},
+	}
+	for _, test := range tests {
+		resized, err := transform.ResizeImage(src, test.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resized.Bounds().Size() != test.size {
+			t.Fatalf("error expected %v for %+v, but got %v", test.size, test.req, resized.Bounds().Size())
+		}
+	}
+
+	// the padding goes above and below the fitted image
+	padded, err := transform.ResizeImage(src, model.ResizeTransformRequest{Width: 8, Height: 8, Mode: model.RESIZE_PAD, Background: "#ff0000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, _, _ := padded.At(4, 0).RGBA(); r>>8 != 255 || g>>8 != 0 {
+		t.Fatalf("error expected red padding, but got (%v, %v)", r>>8, g>>8)
+	}
+
+	// filling with a left gravity keeps the left part of the image
+	filled, err := transform.ResizeImage(src, model.ResizeTransformRequest{Width: 5, Height: 10, Mode: model.RESIZE_FILL, Gravity: model.POSITION_LEFT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := filled.At(0, 0).RGBA(); r>>8 > 10 {
+		t.Fatalf("error expected the left edge, but got red %v", r>>8)
+	}
+}
+
+func TestEncodeFormats(t *testing.T) {
+	for _, format := range []string{transform.IMG_JPEG, transform.IMG_PNG, transform.IMG_GIF, transform.IMG_BMP, transform.IMG_TIFF} {
+		buf := bytes.Buffer{}
+		if _, err := transform.Encode(&buf, model.ImageInfo{Image: newTestImage(6, 4), Format: format}); err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := imageutils.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Format != format {
+			t.Fatalf("error expected %v, but got %v", format, decoded.Format)
+		}
+		if decoded.Image.Bounds() != image.Rect(0, 0, 6, 4) {
+			t.Fatalf("error expected %v, but got %v", image.Rect(0, 0, 6, 4), decoded.Image.Bounds())
+		}
+	}
+}
+
+func newTestAnimation(t *testing.T) model.ImageInfo {
+	colors := []color.Color{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	g := gif.GIF{LoopCount: 3}
+	for _, c := range colors {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), palette.Plan9)
+		for i := range frame.Pix {
+			frame.Pix[i] = uint8(color.Palette(palette.Plan9).Index(c))
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+
+	buf := bytes.Buffer{}
+	if err := gif.EncodeAll(&buf, &g); err != nil {
+		t.Fatal(err)
+	}
+	info, err := imageutils.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Animation == nil || len(info.Animation.Frames) != 3 {
+		t.Fatalf("error expected 3 frames, but got %+v", info.Animation)
+	}
+	return info
+}
+
+func TestApplyAnimation(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := newTestAnimation(t)
+
+	steps := []model.TransformStep{
+		model.NewTransformStep(model.OP_RESIZE, model.ResizeTransformRequest{Width: 4, Height: 4}),
+	}
+	if err := tr.Apply(context.Background(), &info, steps); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	if _, err := transform.Encode(&buf, info); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 3 || g.LoopCount != 3 || g.Delay[2] != 10 {
+		t.Fatalf("error expected 3 frames looping 3 times, but got %v frames, loop %v, delays %v", len(g.Image), g.LoopCount, g.Delay)
+	}
+	if g.Image[1].Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("error expected %v, but got %v", image.Rect(0, 0, 4, 4), g.Image[1].Bounds())
+	}
+}
+
+func TestExtractFrame(t *testing.T) {
+	tr := transform.New(nil, nil)
+	info := newTestAnimation(t)
+
+	steps := []model.TransformStep{model.NewTransformStep(model.OP_FRAME, model.FrameTransformRequest{Index: 1})}
+	if err := tr.Apply(context.Background(), &info, steps); err != nil {
+		t.Fatal(err)
+	}
+	if info.Animation != nil {
+		t.Fatalf("error expected a still image, but got %v frames", len(info.Animation.Frames))
+	}
+	if r, g, _, _ := info.Image.At(0, 0).RGBA(); r>>8 != 0 || g>>8 != 255 {
+		t.Fatalf("error expected the green frame, but got (%v, %v)", r>>8, g>>8)
+	}
+
+	steps = []model.TransformStep{model.NewTransformStep(model.OP_FRAME, model.FrameTransformRequest{Index: 1})}
+	if err := tr.Apply(context.Background(), &info, steps); !errors.Is(err, httputils.ErrBadRequest) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrBadRequest, err)
+	}
+}
+
+func TestAutoOrient(t *testing.T) {
+	tr := transform.New(nil, nil)
+	// orientation 6 means the camera was turned clockwise
+	info := model.ImageInfo{
+		Image:  newTestImage(4, 2),
+		Format: transform.IMG_JPEG,
+		Metadata: &model.ImageMetadata{
+			Orientation: 6,
+			Segments:    []model.MetadataSegment{{Marker: 0xE1, Payload: []byte("Exif\x00\x00II\x2A\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00")}},
+		},
+	}
+
+	steps := []model.TransformStep{
+		model.NewTransformStep(model.OP_AUTO_ORIENT, nil),
+		model.NewTransformStep(model.OP_METADATA, model.MetadataTransformRequest{Mode: model.METADATA_KEEP}),
+	}
+	if err := tr.Apply(context.Background(), &info, steps); err != nil {
+		t.Fatal(err)
+	}
+	if info.Image.Bounds() != image.Rect(0, 0, 2, 4) {
+		t.Fatalf("error expected %v, but got %v", image.Rect(0, 0, 2, 4), info.Image.Bounds())
+	}
+	// the top left pixel comes from the bottom left of the source
+	if r, g, _, _ := info.Image.At(0, 0).RGBA(); r>>8 != 0 || g>>8 != 10 {
+		t.Fatalf("error expected (0, 10), but got (%v, %v)", r>>8, g>>8)
+	}
+
+	buf := bytes.Buffer{}
+	if _, err := transform.Encode(&buf, info); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := imageutils.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Metadata == nil || decoded.Metadata.Orientation != 1 {
+		t.Fatalf("error expected kept metadata with orientation 1, but got %+v", decoded.Metadata)
+	}
+}
+
+func TestHash(t *testing.T) {
+	tr := transform.New(nil, nil)
+	src := model.Image{ID: 1, ImageAttributes: model.ImageAttributes{ContentHash: "abc"}}
+
+	legacy := model.ImageTransformRequestOpts{ResizeTransform: model.ResizeTransformRequest{Width: 300, Height: 200}, Filters: model.FilterTransformRequest{Grayscale: true}}
+	pipeline := model.ImageTransformRequestOpts{Pipeline: []model.TransformStep{
+		{Op: model.OP_GRAYSCALE},
+		{Op: model.OP_RESIZE, Params: []byte(`{ "height": 200, "width": 300, "mode": "" }`)},
+	}}
+	other := model.ImageTransformRequestOpts{ResizeTransform: model.ResizeTransformRequest{Width: 300, Height: 201}, Filters: model.FilterTransformRequest{Grayscale: true}}
+
+	legacyHash, err := tr.Hash(src, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipelineHash, err := tr.Hash(src, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHash, err := tr.Hash(src, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSourceHash, err := tr.Hash(model.Image{ID: 2, ImageAttributes: model.ImageAttributes{ContentHash: "def"}}, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if legacyHash != pipelineHash {
+		t.Fatalf("error expected equivalent requests to have the same hash, but got %v and %v", legacyHash, pipelineHash)
+	}
+	if legacyHash == otherHash || legacyHash == otherSourceHash {
+		t.Fatalf("error expected different requests to have different hashes")
+	}
+}
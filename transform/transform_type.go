@@ -0,0 +1,36 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+// Operation is a named step of a transformation pipeline.
+type Operation interface {
+	// Validate checks the step params without touching any image.
+	Validate(params json.RawMessage) error
+	Apply(ctx context.Context, info *model.ImageInfo, params json.RawMessage) error
+}
+
+type Transformer interface {
+	// Register adds op to the registry, replacing any operation with the same name.
+	Register(name string, op Operation)
+	// Validate checks every requested step names a registered operation with
+	// valid params, errors wrap httputils.ErrBadRequest.
+	Validate(req model.ImageTransformRequestOpts) error
+	// Hash identifies the result of applying req to src: equivalent requests
+	// on the same content give the same hash.
+	Hash(src model.Image, req model.ImageTransformRequestOpts) (string, error)
+	// Apply runs steps in order on info.
+	Apply(ctx context.Context, info *model.ImageInfo, steps []model.TransformStep) error
+	// Transform loads src, applies the requested transformations and stores
+	// the result as a new image of the same owner, reporting how it was encoded.
+	// A result already made from the same content and transformations is
+	// returned instead of being made again.
+	Transform(ctx context.Context, src model.Image, req model.ImageTransformRequestOpts) (model.Image, model.EncodingResult, error)
+	// Render applies the requested transformations to src and returns the
+	// encoded result without storing it.
+	Render(ctx context.Context, src model.Image, req model.ImageTransformRequestOpts) ([]byte, model.EncodingResult, error)
+}
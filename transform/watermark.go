@@ -0,0 +1,219 @@
+package transform
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/colorutils"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	defaultWatermarkOpacity float64 = 0.5
+	defaultWatermarkSize    float64 = 24
+)
+
+var (
+	watermarkFont     *opentype.Font
+	watermarkFontErr  error
+	watermarkFontOnce sync.Once
+)
+
+func (t *TransformerImpl) watermark(ctx context.Context, info *model.ImageInfo, req model.WatermarkTransformRequest) error {
+	var mark image.Image
+	var err error
+	if req.ImageID != 0 {
+		mark, err = t.loadWatermarkImage(ctx, req)
+	} else {
+		mark, err = renderWatermarkText(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the mark is loaded once and drawn on every frame
+	if info.Animation != nil {
+		for i, frame := range info.Animation.Frames {
+			info.Animation.Frames[i] = drawWatermark(frame, mark, req)
+		}
+		info.Image = info.Animation.Frames[0]
+		return nil
+	}
+	info.Image = drawWatermark(info.Image, mark, req)
+	return nil
+}
+
+func drawWatermark(img image.Image, mark image.Image, req model.WatermarkTransformRequest) image.Image {
+	opacity := req.Opacity
+	if opacity == 0 {
+		opacity = defaultWatermarkOpacity
+	}
+	position := req.Position
+	if position == "" {
+		position = model.POSITION_BOTTOM_RIGHT
+	}
+
+	bounds := img.Bounds()
+	base := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(base, base.Bounds(), img, bounds.Min, draw.Src)
+
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(opacity * 255))})
+	size := mark.Bounds().Size()
+	margin := int(req.Margin)
+
+	drawMark := func(pt image.Point) {
+		r := image.Rectangle{Min: pt, Max: pt.Add(size)}
+		draw.DrawMask(base, r, mark, mark.Bounds().Min, mask, image.Point{}, draw.Over)
+	}
+
+	if req.Tile {
+		// tiles start from the anchor so the pattern lines up with it
+		origin := anchorPoint(base.Bounds(), size, position, margin)
+		stepX, stepY := size.X+margin, size.Y+margin
+		startX := origin.X - (origin.X/stepX+1)*stepX
+		startY := origin.Y - (origin.Y/stepY+1)*stepY
+		for y := startY; y < base.Bounds().Dy(); y += stepY {
+			for x := startX; x < base.Bounds().Dx(); x += stepX {
+				drawMark(image.Pt(x, y))
+			}
+		}
+	} else {
+		drawMark(anchorPoint(base.Bounds(), size, position, margin))
+	}
+	return base
+}
+
+func (t *TransformerImpl) loadWatermarkImage(ctx context.Context, req model.WatermarkTransformRequest) (image.Image, error) {
+	ownerID, ok := OwnerFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("watermark image owner is unknown")
+	}
+
+	src, err := t.imageRepo.GetImage(ctx, ownerID, req.ImageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: watermark image %d", httputils.ErrNotFound, req.ImageID)
+		}
+		return nil, err
+	}
+	markData, err := t.resource.LoadImage(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	mark := markData.Image
+	if req.Width > 0 {
+		bounds := mark.Bounds()
+		if err := checkResultSize(int(req.Width), scaledSide(float64(bounds.Dy()), float64(req.Width)/float64(bounds.Dx()))); err != nil {
+			return nil, err
+		}
+		mark = imaging.Resize(mark, int(req.Width), 0, imaging.Lanczos)
+	}
+	if err := checkMarkSize(mark.Bounds().Size()); err != nil {
+		return nil, err
+	}
+	return mark, nil
+}
+
+func renderWatermarkText(req model.WatermarkTransformRequest) (image.Image, error) {
+	watermarkFontOnce.Do(func() {
+		watermarkFont, watermarkFontErr = opentype.Parse(goregular.TTF)
+	})
+	if watermarkFontErr != nil {
+		return nil, watermarkFontErr
+	}
+
+	size := req.Size
+	if size == 0 {
+		size = defaultWatermarkSize
+	}
+	face, err := opentype.NewFace(watermarkFont, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	var textColor color.Color = color.White
+	if req.Color != "" {
+		if textColor, err = colorutils.ParseColor(req.Color); err != nil {
+			return nil, err
+		}
+	}
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, req.Text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	if err := checkMarkSize(image.Pt(width, height)); err != nil {
+		return nil, err
+	}
+	if err := checkResultSize(width, height); err != nil {
+		return nil, err
+	}
+
+	text := image.NewNRGBA(image.Rect(0, 0, width, height))
+	drawer := font.Drawer{
+		Dst:  text,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.Point26_6{X: 0, Y: metrics.Ascent},
+	}
+	drawer.DrawString(req.Text)
+	return text, nil
+}
+
+// checkMarkSize rejects empty marks, tiles are stepped by their size.
+func checkMarkSize(size image.Point) error {
+	if size.X <= 0 || size.Y <= 0 {
+		return fmt.Errorf("%w: watermark is empty", httputils.ErrBadRequest)
+	}
+	return nil
+}
+
+// anchorPoint returns where an overlay of the given size is drawn inside
+// bounds so it sits at position, keeping margin from the edges it touches.
+func anchorPoint(bounds image.Rectangle, size image.Point, position string, margin int) image.Point {
+	left := bounds.Min.X + margin
+	centerX := bounds.Min.X + (bounds.Dx()-size.X)/2
+	right := bounds.Max.X - size.X - margin
+	top := bounds.Min.Y + margin
+	centerY := bounds.Min.Y + (bounds.Dy()-size.Y)/2
+	bottom := bounds.Max.Y - size.Y - margin
+
+	switch position {
+	case model.POSITION_TOP_LEFT:
+		return image.Pt(left, top)
+	case model.POSITION_TOP:
+		return image.Pt(centerX, top)
+	case model.POSITION_TOP_RIGHT:
+		return image.Pt(right, top)
+	case model.POSITION_LEFT:
+		return image.Pt(left, centerY)
+	case model.POSITION_CENTER:
+		return image.Pt(centerX, centerY)
+	case model.POSITION_RIGHT:
+		return image.Pt(right, centerY)
+	case model.POSITION_BOTTOM_LEFT:
+		return image.Pt(left, bottom)
+	case model.POSITION_BOTTOM:
+		return image.Pt(centerX, bottom)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
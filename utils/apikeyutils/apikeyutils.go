@@ -0,0 +1,58 @@
+package apikeyutils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// keys look like ipk_<prefix>_<secret>, the prefix identifies the key and is
+// stored in clear, only a bcrypt hash of the whole key is kept.
+const (
+	keyType     = "ipk"
+	prefixBytes = 6
+	secretBytes = 24
+)
+
+var ErrInvalidKey = errors.New("invalid api key")
+
+// NewAPIKey returns a random key and its prefix.
+func NewAPIKey() (key string, prefix string, err error) {
+	b := make([]byte, prefixBytes+secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(b[:prefixBytes])
+	secret := base64.RawURLEncoding.EncodeToString(b[prefixBytes:])
+	return strings.Join([]string{keyType, prefix, secret}, "_"), prefix, nil
+}
+
+// Prefix returns the prefix of a key made by NewAPIKey.
+func Prefix(key string) (string, error) {
+	rest, found := strings.CutPrefix(key, keyType+"_")
+	if !found {
+		return "", ErrInvalidKey
+	}
+	prefix, secret, found := strings.Cut(rest, "_")
+	if !found || len(prefix) != prefixBytes*2 || secret == "" {
+		return "", ErrInvalidKey
+	}
+	if _, err := hex.DecodeString(prefix); err != nil {
+		return "", ErrInvalidKey
+	}
+	return prefix, nil
+}
+
+func HashAPIKey(key string) (string, error) {
+	return utils.EncryptString(key)
+}
+
+// CompareAPIKey reports whether key matches a hash made by HashAPIKey.
+func CompareAPIKey(hash string, key string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(key)) == nil
+}
@@ -0,0 +1,46 @@
+package apikeyutils_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/utils/apikeyutils"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, err := apikeyutils.NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := apikeyutils.Prefix(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != prefix {
+		t.Fatalf("error expected %v, but got %v", prefix, parsed)
+	}
+
+	hash, err := apikeyutils.HashAPIKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !apikeyutils.CompareAPIKey(hash, key) {
+		t.Fatalf("error expected %v, but got %v", true, false)
+	}
+	other, _, err := apikeyutils.NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apikeyutils.CompareAPIKey(hash, other) {
+		t.Fatalf("error expected %v, but got %v", false, true)
+	}
+}
+
+func TestPrefixInvalid(t *testing.T) {
+	keys := []string{"", "ipk_", "ipk_0123456789ab", "ipk_0123456789ab_", "abc_0123456789ab_secret", "ipk_short_secret", "ipk_zz23456789ab_secret"}
+	for _, key := range keys {
+		if _, err := apikeyutils.Prefix(key); !errors.Is(err, apikeyutils.ErrInvalidKey) {
+			t.Fatalf("%q: error expected %v, but got %v", key, apikeyutils.ErrInvalidKey, err)
+		}
+	}
+}
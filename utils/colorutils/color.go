@@ -0,0 +1,44 @@
+package colorutils
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+var namedColors = map[string]color.Color{
+	"black":       color.Black,
+	"white":       color.White,
+	"transparent": color.Transparent,
+}
+
+// ParseColor parses a named color (black, white, transparent) or a hex color
+// written as #RGB, #RRGGBB or #RRGGBBAA.
+func ParseColor(s string) (color.Color, error) {
+	if c, found := namedColors[strings.ToLower(s)]; found {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 || !strings.HasPrefix(s, "#") {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{
+		R: uint8(v >> 24),
+		G: uint8(v >> 16),
+		B: uint8(v >> 8),
+		A: uint8(v),
+	}, nil
+}
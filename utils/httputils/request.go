@@ -83,7 +83,7 @@ func ParseRequestBody(r *http.Request, dst any) error {
 	}
 
 	if err := json.Unmarshal(data, &dst); err != nil {
-		return err
+		return fmt.Errorf("%w: %v", ErrBadRequest, err)
 	}
 	return nil
 }
@@ -103,6 +103,10 @@ func ParseURLValues(vals url.Values, dst any) error {
 			continue
 		}
 		formVal := vals.Get(formTag)
+		if formVal == "" {
+			// missing values keep the field's current value
+			continue
+		}
 
 		switch val.Kind() {
 		case reflect.String:
@@ -26,15 +26,6 @@ func SendResponse(w http.ResponseWriter, message string, data any, meta any, err
 	fmt.Fprint(w, string(jsonBody))
 }
 
-func unwrapErrors(err error) []error {
-	errs := []error{}
-	for err != nil {
-		errs = append(errs, err)
-		err = errors.Unwrap(err)
-	}
-
-	return errs
-}
 func unwrapErrorStrs(err error) []string {
 	errs := []string{}
 	for err != nil {
@@ -46,25 +37,29 @@ func unwrapErrorStrs(err error) []string {
 }
 
 func findCode(err error) (int, APICode) {
-	errs := unwrapErrors(err)
-	// Get first error
-	if len(errs) == 0 {
+	if err == nil {
 		return http.StatusOK, SUCCESS
 	}
 
-	tarErr := errs[0]
-	switch tarErr {
-	case ErrBadRequest:
+	// errors wrapping one of the pre-defined errors (fmt.Errorf("%w: ...")) get its code
+	switch {
+	case errors.Is(err, ErrBadRequest):
 		return http.StatusBadRequest, BAD_REQUEST
-	case ErrForbidden:
+	case errors.Is(err, ErrForbidden):
 		return http.StatusForbidden, FORBIDDEN
-	case ErrUnauthorized:
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, NOT_FOUND
+	case errors.Is(err, ErrPayloadTooLarge):
+		return http.StatusRequestEntityTooLarge, PAYLOAD_TOO_LARGE
+	case errors.Is(err, ErrUnsupportedMedia):
+		return http.StatusUnsupportedMediaType, UNSUPPORTED_MEDIA
+	case errors.Is(err, ErrUnauthorized):
 		return http.StatusUnauthorized, UNAUTHORIZED
-	case ErrAccessTokenExpired:
+	case errors.Is(err, ErrAccessTokenExpired):
 		return http.StatusUnauthorized, ACCESS_TOKEN_EXPIRED
-	case ErrRefreshTokenExpired:
+	case errors.Is(err, ErrRefreshTokenExpired):
 		return http.StatusUnauthorized, REFRESH_TOKEN_EXPIRED
-	case ErrTokenRevoked:
+	case errors.Is(err, ErrTokenRevoked):
 		return http.StatusUnauthorized, TOKEN_REVOKED
 	default:
 		return http.StatusInternalServerError, INTERNAL_SERVER
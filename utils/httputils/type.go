@@ -21,6 +21,9 @@ const (
 	BAD_REQUEST           APICode = "bad_request"
 	INTERNAL_SERVER       APICode = "internal_server"
 	FORBIDDEN             APICode = "forbidden"
+	NOT_FOUND             APICode = "not_found"
+	PAYLOAD_TOO_LARGE     APICode = "payload_too_large"
+	UNSUPPORTED_MEDIA     APICode = "unsupported_media_type"
 	SUCCESS               APICode = "success"
 	UNAUTHORIZED          APICode = "unauthorized"
 	TOKEN_REVOKED         APICode = "token_revoked"
@@ -33,6 +36,9 @@ var (
 	Success                string = "success"
 	ErrBadRequest          error  = fmt.Errorf("bad request")
 	ErrForbidden           error  = fmt.Errorf("forbidden")
+	ErrNotFound            error  = fmt.Errorf("not found")
+	ErrPayloadTooLarge     error  = fmt.Errorf("payload too large")
+	ErrUnsupportedMedia    error  = fmt.Errorf("unsupported media type")
 	ErrUnauthorized        error  = fmt.Errorf("unauthorized")
 	ErrTokenRevoked        error  = fmt.Errorf("token revoked")
 	ErrAccessTokenExpired  error  = fmt.Errorf("access token expired")
@@ -0,0 +1,107 @@
+package imageutils
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Decode reads an image in any of the supported formats (jpeg, png, gif,
+// bmp, tiff and webp). Every frame of an animated GIF is kept in
+// ImageInfo.Animation and the metadata of a JPEG in ImageInfo.Metadata.
+func Decode(r io.Reader) (model.ImageInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return model.ImageInfo{}, err
+	}
+
+	loadedImage, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return model.ImageInfo{}, err
+	}
+
+	info := model.ImageInfo{
+		Image:  loadedImage,
+		Format: format,
+	}
+	if format == "jpeg" {
+		info.Metadata = ReadJPEGMetadata(data)
+	}
+	if format == "gif" {
+		// every frame is allocated by DecodeAll, check them before
+		config, err := gif.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return model.ImageInfo{}, err
+		}
+		frames, err := GIFFrameCount(data)
+		if err != nil {
+			return model.ImageInfo{}, err
+		}
+		if err := CheckAnimationSize(frames, config); err != nil {
+			return model.ImageInfo{}, err
+		}
+
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return model.ImageInfo{}, err
+		}
+		if len(g.Image) > 1 {
+			if info.Animation, err = coalesce(g); err != nil {
+				return model.ImageInfo{}, err
+			}
+			info.Image = info.Animation.Frames[0]
+		}
+	}
+	return info, nil
+}
+
+// coalesce renders every GIF frame onto the full canvas, following the
+// disposal method of the previous frames. It fails with ErrAnimationTooLarge
+// when the canvases would take more pixels than an image can have.
+func coalesce(g *gif.GIF) (*model.Animation, error) {
+	if err := CheckAnimationSize(len(g.Image), g.Config); err != nil {
+		return nil, err
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	animation := model.Animation{
+		Frames:    make([]image.Image, 0, len(g.Image)),
+		Delays:    make([]int, len(g.Image)),
+		Disposals: make([]byte, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+	copy(animation.Delays, g.Delay)
+	copy(animation.Disposals, g.Disposal)
+
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		if animation.Disposals[i] == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		animation.Frames = append(animation.Frames, cloneRGBA(canvas))
+
+		switch animation.Disposals[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return &animation, nil
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Bounds())
+	copy(clone.Pix, img.Pix)
+	return clone
+}
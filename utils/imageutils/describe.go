@@ -0,0 +1,76 @@
+package imageutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+var colorModelNames = map[color.Model]string{
+	color.RGBAModel:    "rgba",
+	color.RGBA64Model:  "rgba64",
+	color.NRGBAModel:   "nrgba",
+	color.NRGBA64Model: "nrgba64",
+	color.AlphaModel:   "alpha",
+	color.Alpha16Model: "alpha16",
+	color.GrayModel:    "gray",
+	color.Gray16Model:  "gray16",
+	color.CMYKModel:    "cmyk",
+	color.YCbCrModel:   "ycbcr",
+	color.NYCbCrAModel: "nycbcra",
+}
+
+// Describe decodes an image file and returns its attributes.
+func Describe(data []byte) (model.ImageAttributes, error) {
+	info, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return model.ImageAttributes{}, err
+	}
+
+	hash := sha256.Sum256(data)
+	bounds := info.Image.Bounds()
+	attributes := model.ImageAttributes{
+		Width:       int64(bounds.Dx()),
+		Height:      int64(bounds.Dy()),
+		Format:      info.Format,
+		ByteSize:    int64(len(data)),
+		ColorModel:  colorModelName(info.Image.ColorModel()),
+		HasAlpha:    hasAlpha(info.Image),
+		ContentHash: hex.EncodeToString(hash[:]),
+	}
+	if info.Format == "jpeg" {
+		attributes.EXIF = ReadEXIF(data)
+	}
+	return attributes, nil
+}
+
+func colorModelName(m color.Model) string {
+	if _, ok := m.(color.Palette); ok {
+		return "paletted"
+	}
+	if name, found := colorModelNames[m]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// hasAlpha reports whether any pixel of the image is not fully opaque.
+func hasAlpha(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return !opaque.Opaque()
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,518 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+)
+
+const (
+	markerSOI  byte = 0xD8
+	markerEOI  byte = 0xD9
+	markerSOS  byte = 0xDA
+	markerAPP1 byte = 0xE1
+	markerAPP2 byte = 0xE2
+)
+
+const (
+	TAG_ORIENTATION uint16 = 0x0112
+	TAG_EXIF_IFD    uint16 = 0x8769
+	TAG_GPS_IFD     uint16 = 0x8825
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// exif value sizes by tiff type
+var exifTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+type jpegSegment struct {
+	marker byte
+	// offset of the payload in the file
+	offset  int
+	payload []byte
+}
+
+// jpegSegments lists the segments of a JPEG header, up to the start of the
+// image data. ok is false when data is not a JPEG.
+func jpegSegments(data []byte) (segments []jpegSegment, ok bool) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil, false
+	}
+
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xFF {
+			// fill byte
+			i++
+			continue
+		}
+		if marker == markerSOS || marker == markerEOI {
+			break
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			// markers without a length
+			i += 2
+			continue
+		}
+
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || end < i+4 {
+			break
+		}
+		segments = append(segments, jpegSegment{marker: marker, offset: i + 4, payload: data[i+4 : end]})
+		i = end
+	}
+	return segments, true
+}
+
+func isMetadataSegment(segment jpegSegment) bool {
+	switch segment.marker {
+	case markerAPP1:
+		return bytes.HasPrefix(segment.payload, exifHeader) || bytes.HasPrefix(segment.payload, xmpHeader)
+	case markerAPP2:
+		return bytes.HasPrefix(segment.payload, iccHeader)
+	}
+	return false
+}
+
+// ReadJPEGMetadata returns the EXIF, XMP and ICC segments of a JPEG, or nil
+// when there are none.
+func ReadJPEGMetadata(data []byte) *model.ImageMetadata {
+	segments, ok := jpegSegments(data)
+	if !ok {
+		return nil
+	}
+
+	metadata := model.ImageMetadata{Orientation: 1}
+	for _, segment := range segments {
+		if !isMetadataSegment(segment) {
+			continue
+		}
+		metadata.Segments = append(metadata.Segments, model.MetadataSegment{
+			Marker:  segment.marker,
+			Payload: bytes.Clone(segment.payload),
+		})
+		if segment.marker == markerAPP1 && bytes.HasPrefix(segment.payload, exifHeader) {
+			if orientation, found := exifOrientation(segment.payload[len(exifHeader):]); found {
+				metadata.Orientation = orientation
+			}
+		}
+	}
+	if len(metadata.Segments) == 0 {
+		return nil
+	}
+	return &metadata
+}
+
+// SetOrientation rewrites the EXIF orientation of the metadata.
+func SetOrientation(metadata *model.ImageMetadata, orientation int) {
+	metadata.Orientation = orientation
+	for _, segment := range metadata.Segments {
+		if segment.Marker != markerAPP1 || !bytes.HasPrefix(segment.Payload, exifHeader) {
+			continue
+		}
+		tiff := segment.Payload[len(exifHeader):]
+		order, ifd0, ok := tiffHeader(tiff)
+		if !ok {
+			continue
+		}
+		walkIFD(tiff, order, ifd0, func(entry int, tag uint16) {
+			if tag == TAG_ORIENTATION {
+				order.PutUint16(tiff[entry+8:], uint16(orientation))
+			}
+		})
+	}
+}
+
+// EmbedJPEGMetadata writes the metadata segments right after the start of
+// an encoded JPEG.
+func EmbedJPEGMetadata(encoded []byte, metadata *model.ImageMetadata) []byte {
+	if metadata == nil || len(encoded) < 2 {
+		return encoded
+	}
+
+	out := bytes.Buffer{}
+	out.Write(encoded[:2])
+	for _, segment := range metadata.Segments {
+		out.Write([]byte{0xFF, segment.Marker})
+		out.Write(binary.BigEndian.AppendUint16(nil, uint16(len(segment.Payload)+2)))
+		out.Write(segment.Payload)
+	}
+	out.Write(encoded[2:])
+	return out.Bytes()
+}
+
+// StripGPS returns a copy of a JPEG, TIFF, PNG or WebP image with the EXIF
+// GPS data removed, other data is returned unchanged.
+func StripGPS(data []byte) []byte {
+	switch Sniff(data) {
+	case "jpeg":
+		return stripJPEGGPS(data)
+	case "tiff":
+		stripped := bytes.Clone(data)
+		stripTIFFGPS(stripped)
+		return stripped
+	case "png":
+		return stripPNGGPS(data)
+	case "webp":
+		return stripWebPGPS(data)
+	}
+	return data
+}
+
+func stripJPEGGPS(data []byte) []byte {
+	segments, _ := jpegSegments(data)
+	stripped := bytes.Clone(data)
+	for _, segment := range segments {
+		if segment.marker != markerAPP1 || !bytes.HasPrefix(segment.payload, exifHeader) {
+			continue
+		}
+		stripTIFFGPS(stripped[segment.offset+len(exifHeader) : segment.offset+len(segment.payload)])
+	}
+	return stripped
+}
+
+// stripPNGGPS strips the eXIf chunk, whose checksum is then updated.
+func stripPNGGPS(data []byte) []byte {
+	stripped := bytes.Clone(data)
+	// chunks are a length, a type, the data and a crc of the type and data
+	pos := 8
+	for pos+12 <= len(stripped) {
+		length := int(binary.BigEndian.Uint32(stripped[pos:]))
+		end := pos + 12 + length
+		if end > len(stripped) {
+			break
+		}
+		if string(stripped[pos+4:pos+8]) == "eXIf" {
+			stripTIFFGPS(trimExifHeader(stripped[pos+8 : pos+8+length]))
+			binary.BigEndian.PutUint32(stripped[pos+8+length:], crc32.ChecksumIEEE(stripped[pos+4:pos+8+length]))
+		}
+		pos = end
+	}
+	return stripped
+}
+
+// stripWebPGPS strips the EXIF chunk of the RIFF container.
+func stripWebPGPS(data []byte) []byte {
+	stripped := bytes.Clone(data)
+	// chunks are a type, a little endian size and the data padded to an even
+	// size
+	pos := 12
+	for pos+8 <= len(stripped) {
+		size := int(binary.LittleEndian.Uint32(stripped[pos+4:]))
+		end := pos + 8 + size
+		if end > len(stripped) {
+			break
+		}
+		if string(stripped[pos:pos+4]) == "EXIF" {
+			stripTIFFGPS(trimExifHeader(stripped[pos+8 : end]))
+		}
+		pos = end + size%2
+	}
+	return stripped
+}
+
+// trimExifHeader removes the JPEG style header some writers also put in
+// PNG and WebP EXIF chunks.
+func trimExifHeader(payload []byte) []byte {
+	return bytes.TrimPrefix(payload, exifHeader)
+}
+
+// maxIFDs bounds the IFD chain followed by stripTIFFGPS, in case it loops.
+const maxIFDs = 64
+
+// stripTIFFGPS empties, in place, the GPS IFDs pointed to by the IFDs of a
+// tiff structure, TIFF files chain an IFD per page.
+func stripTIFFGPS(tiff []byte) {
+	order, offset, ok := tiffHeader(tiff)
+	if !ok {
+		return
+	}
+	for i := 0; i < maxIFDs && offset > 0 && offset+2 <= len(tiff); i++ {
+		walkIFD(tiff, order, offset, func(entry int, tag uint16) {
+			if tag == TAG_GPS_IFD {
+				clearIFD(tiff, order, int(order.Uint32(tiff[entry+8:])))
+			}
+		})
+		next := offset + 2 + int(order.Uint16(tiff[offset:]))*12
+		if next+4 > len(tiff) {
+			return
+		}
+		offset = int(order.Uint32(tiff[next:]))
+	}
+}
+
+func tiffHeader(tiff []byte) (binary.ByteOrder, int, bool) {
+	if len(tiff) < 8 {
+		return nil, 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, false
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil, 0, false
+	}
+	return order, int(order.Uint32(tiff[4:])), true
+}
+
+// walkIFD calls fn with the offset of every entry of the IFD at offset.
+func walkIFD(tiff []byte, order binary.ByteOrder, offset int, fn func(entry int, tag uint16)) {
+	if offset < 0 || offset+2 > len(tiff) {
+		return
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return
+		}
+		fn(entry, order.Uint16(tiff[entry:]))
+	}
+}
+
+// entryValue returns the bytes holding the value of an IFD entry, inline or
+// at its offset.
+func entryValue(tiff []byte, order binary.ByteOrder, entry int) []byte {
+	size := exifTypeSizes[order.Uint16(tiff[entry+2:])] * int(order.Uint32(tiff[entry+4:]))
+	if size <= 4 {
+		return tiff[entry+8 : entry+8+size]
+	}
+	offset := int(order.Uint32(tiff[entry+8:]))
+	if offset < 0 || offset+size > len(tiff) {
+		return nil
+	}
+	return tiff[offset : offset+size]
+}
+
+// clearIFD zeroes every entry of an IFD and its values, leaving an empty IFD.
+func clearIFD(tiff []byte, order binary.ByteOrder, offset int) {
+	walkIFD(tiff, order, offset, func(entry int, tag uint16) {
+		clear(entryValue(tiff, order, entry))
+		clear(tiff[entry : entry+12])
+	})
+	if offset >= 0 && offset+2 <= len(tiff) {
+		order.PutUint16(tiff[offset:], 0)
+	}
+}
+
+func exifOrientation(tiff []byte) (int, bool) {
+	order, ifd0, ok := tiffHeader(tiff)
+	if !ok {
+		return 0, false
+	}
+	orientation, found := 0, false
+	walkIFD(tiff, order, ifd0, func(entry int, tag uint16) {
+		if tag == TAG_ORIENTATION {
+			orientation, found = int(order.Uint16(tiff[entry+8:])), true
+		}
+	})
+	if orientation < 1 || orientation > 8 {
+		return 0, false
+	}
+	return orientation, found
+}
+
+// exifTagNames names the tags returned by ParseEXIF, other tags are named
+// by their hex id.
+var exifTagNames = map[uint16]string{
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x011A: "XResolution",
+	0x011B: "YResolution",
+	0x0128: "ResolutionUnit",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x0213: "YCbCrPositioning",
+	0x8298: "Copyright",
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8822: "ExposureProgram",
+	0x8827: "ISOSpeedRatings",
+	0x9000: "ExifVersion",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9010: "OffsetTime",
+	0x9011: "OffsetTimeOriginal",
+	0x9201: "ShutterSpeedValue",
+	0x9202: "ApertureValue",
+	0x9203: "BrightnessValue",
+	0x9204: "ExposureBiasValue",
+	0x9205: "MaxApertureValue",
+	0x9207: "MeteringMode",
+	0x9208: "LightSource",
+	0x9209: "Flash",
+	0x920A: "FocalLength",
+	0x9290: "SubSecTime",
+	0x9291: "SubSecTimeOriginal",
+	0x9292: "SubSecTimeDigitized",
+	0xA001: "ColorSpace",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA402: "ExposureMode",
+	0xA403: "WhiteBalance",
+	0xA404: "DigitalZoomRatio",
+	0xA405: "FocalLengthIn35mmFilm",
+	0xA406: "SceneCaptureType",
+	0xA420: "ImageUniqueID",
+	0xA432: "LensSpecification",
+	0xA433: "LensMake",
+	0xA434: "LensModel",
+}
+
+var gpsTagNames = map[uint16]string{
+	0x0000: "GPSVersionID",
+	0x0001: "GPSLatitudeRef",
+	0x0002: "GPSLatitude",
+	0x0003: "GPSLongitudeRef",
+	0x0004: "GPSLongitude",
+	0x0005: "GPSAltitudeRef",
+	0x0006: "GPSAltitude",
+	0x0007: "GPSTimeStamp",
+	0x0010: "GPSImgDirectionRef",
+	0x0011: "GPSImgDirection",
+	0x001D: "GPSDateStamp",
+}
+
+// tags skipped by ParseEXIF, the maker note is vendor specific binary data
+const tagMakerNote uint16 = 0x927C
+
+// ReadEXIF parses the EXIF of a JPEG, see ParseEXIF. It returns nil when the
+// data is not a JPEG or has no EXIF.
+func ReadEXIF(data []byte) model.EXIF {
+	segments, _ := jpegSegments(data)
+	for _, segment := range segments {
+		if segment.marker == markerAPP1 && bytes.HasPrefix(segment.payload, exifHeader) {
+			return ParseEXIF(segment.payload[len(exifHeader):])
+		}
+	}
+	return nil
+}
+
+// ParseEXIF returns the tags of IFD0, the Exif IFD and the GPS IFD of a tiff
+// structure. Numbers are returned as numbers, rationals as floats and lists
+// of them as arrays.
+func ParseEXIF(tiff []byte) model.EXIF {
+	order, ifd0, ok := tiffHeader(tiff)
+	if !ok {
+		return nil
+	}
+
+	exif := model.EXIF{}
+	var readIFD func(offset int, names map[uint16]string, depth int)
+	readIFD = func(offset int, names map[uint16]string, depth int) {
+		walkIFD(tiff, order, offset, func(entry int, tag uint16) {
+			switch {
+			case tag == TAG_EXIF_IFD && depth == 0:
+				readIFD(int(order.Uint32(tiff[entry+8:])), exifTagNames, depth+1)
+				return
+			case tag == TAG_GPS_IFD && depth == 0:
+				readIFD(int(order.Uint32(tiff[entry+8:])), gpsTagNames, depth+1)
+				return
+			case tag == tagMakerNote:
+				return
+			}
+
+			value, ok := entryValueOf(tiff, order, entry)
+			if !ok {
+				return
+			}
+			name, found := names[tag]
+			if !found {
+				name = fmt.Sprintf("0x%04X", tag)
+			}
+			exif[name] = value
+		})
+	}
+	readIFD(ifd0, exifTagNames, 0)
+
+	if len(exif) == 0 {
+		return nil
+	}
+	return exif
+}
+
+// entryValueOf decodes the value of an IFD entry.
+func entryValueOf(tiff []byte, order binary.ByteOrder, entry int) (any, bool) {
+	typ := order.Uint16(tiff[entry+2:])
+	count := int(order.Uint32(tiff[entry+4:]))
+	raw := entryValue(tiff, order, entry)
+	if raw == nil || count == 0 {
+		return nil, false
+	}
+
+	switch typ {
+	case 2:
+		return strings.TrimRight(string(raw), "\x00 "), true
+	case 7:
+		// undefined values are only kept when they are readable
+		text := strings.TrimRight(string(raw), "\x00 ")
+		for _, r := range text {
+			if r < 0x20 || r > 0x7E {
+				return nil, false
+			}
+		}
+		return text, true
+	}
+
+	size := exifTypeSizes[typ]
+	if size == 0 {
+		return nil, false
+	}
+	values := make([]any, 0, count)
+	for i := 0; i < count; i++ {
+		b := raw[i*size:]
+		switch typ {
+		case 1:
+			values = append(values, int64(b[0]))
+		case 6:
+			values = append(values, int64(int8(b[0])))
+		case 3:
+			values = append(values, int64(order.Uint16(b)))
+		case 8:
+			values = append(values, int64(int16(order.Uint16(b))))
+		case 4:
+			values = append(values, int64(order.Uint32(b)))
+		case 9:
+			values = append(values, int64(int32(order.Uint32(b))))
+		case 5, 10:
+			num, den := float64(order.Uint32(b)), float64(order.Uint32(b[4:]))
+			if typ == 10 {
+				num, den = float64(int32(order.Uint32(b))), float64(int32(order.Uint32(b[4:])))
+			}
+			if den == 0 {
+				values = append(values, nil)
+				continue
+			}
+			values = append(values, num/den)
+		case 11:
+			values = append(values, float64(math.Float32frombits(order.Uint32(b))))
+		case 12:
+			values = append(values, math.Float64frombits(order.Uint64(b)))
+		}
+	}
+	if len(values) == 1 {
+		return values[0], true
+	}
+	return values, true
+}
@@ -0,0 +1,143 @@
+package imageutils_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+)
+
+var gpsLatitude = []byte{0x11, 0x22, 0x33, 0x44}
+
+// newTestEXIF returns a tiff structure with an orientation and a GPS
+// latitude.
+func newTestEXIF(orientation uint16) []byte {
+	le := binary.LittleEndian
+	tiff := []byte("II\x2A\x00\x08\x00\x00\x00")
+	// IFD0: orientation and GPS pointer
+	tiff = le.AppendUint16(tiff, 2)
+	tiff = append(le.AppendUint16(le.AppendUint16(tiff, imageutils.TAG_ORIENTATION), 3), 1, 0, 0, 0)
+	tiff = append(le.AppendUint16(tiff, orientation), 0, 0)
+	tiff = append(le.AppendUint16(le.AppendUint16(tiff, imageutils.TAG_GPS_IFD), 4), 1, 0, 0, 0)
+	tiff = le.AppendUint32(tiff, 38)
+	tiff = le.AppendUint32(tiff, 0)
+	// GPS IFD: latitude as 3 rationals stored at 56
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = append(le.AppendUint16(le.AppendUint16(tiff, 2), 5), 3, 0, 0, 0)
+	tiff = le.AppendUint32(tiff, 56)
+	tiff = le.AppendUint32(tiff, 0)
+	for i := 0; i < 6; i++ {
+		tiff = append(tiff, gpsLatitude...)
+	}
+	return tiff
+}
+
+// newTestJPEG returns a 4x2 JPEG with an EXIF orientation and a GPS latitude.
+func newTestJPEG(t *testing.T, orientation uint16) []byte {
+	tiff := newTestEXIF(orientation)
+	buf := bytes.Buffer{}
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return imageutils.EmbedJPEGMetadata(buf.Bytes(), &model.ImageMetadata{
+		Segments: []model.MetadataSegment{{Marker: 0xE1, Payload: append([]byte("Exif\x00\x00"), tiff...)}},
+	})
+}
+
+func TestReadJPEGMetadata(t *testing.T) {
+	data := newTestJPEG(t, 6)
+
+	metadata := imageutils.ReadJPEGMetadata(data)
+	if metadata == nil || metadata.Orientation != 6 {
+		t.Fatalf("error expected orientation 6, but got %+v", metadata)
+	}
+
+	imageutils.SetOrientation(metadata, 1)
+	reread := imageutils.ReadJPEGMetadata(imageutils.EmbedJPEGMetadata(data[:2], metadata))
+	if reread == nil || reread.Orientation != 1 {
+		t.Fatalf("error expected orientation 1, but got %+v", reread)
+	}
+}
+
+func TestStripGPS(t *testing.T) {
+	data := newTestJPEG(t, 1)
+
+	stripped := imageutils.StripGPS(data)
+	if bytes.Contains(stripped, gpsLatitude) {
+		t.Fatalf("error expected the gps latitude to be removed")
+	}
+	if !bytes.Contains(data, gpsLatitude) {
+		t.Fatalf("error expected the source to be left untouched")
+	}
+	if _, err := imageutils.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStripGPSContainers(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+	// the eXIf chunk goes after the 8 byte signature and the 25 byte IHDR
+	encoded := buf.Bytes()
+	exifChunk := binary.BigEndian.AppendUint32(nil, uint32(len(newTestEXIF(1))))
+	exifChunk = append(exifChunk, "eXIf"...)
+	exifChunk = append(exifChunk, newTestEXIF(1)...)
+	exifChunk = binary.BigEndian.AppendUint32(exifChunk, crc32.ChecksumIEEE(exifChunk[4:]))
+	pngData := append(append(bytes.Clone(encoded[:33]), exifChunk...), encoded[33:]...)
+
+	webpChunks := append([]byte("VP8X"), 10, 0, 0, 0)
+	webpChunks = append(webpChunks, make([]byte, 10)...)
+	webpChunks = append(webpChunks, "EXIF"...)
+	webpChunks = binary.LittleEndian.AppendUint32(webpChunks, uint32(len(newTestEXIF(1))+6))
+	webpChunks = append(append(webpChunks, "Exif\x00\x00"...), newTestEXIF(1)...)
+	webpData := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(webpChunks)+4))...)
+	webpData = append(append(webpData, "WEBP"...), webpChunks...)
+
+	tests := map[string][]byte{
+		"tiff": newTestEXIF(1),
+		"png":  pngData,
+		"webp": webpData,
+	}
+	for format, data := range tests {
+		if imageutils.Sniff(data) != format || !bytes.Contains(data, gpsLatitude) {
+			t.Fatalf("%s: error expected a %s with a gps latitude", format, format)
+		}
+		if bytes.Contains(imageutils.StripGPS(data), gpsLatitude) {
+			t.Fatalf("%s: error expected the gps latitude to be removed", format)
+		}
+	}
+
+	// the png checksum is updated
+	if _, err := png.Decode(bytes.NewReader(imageutils.StripGPS(pngData))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	data := newTestJPEG(t, 6)
+
+	attributes, err := imageutils.Describe(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attributes.Width != 4 || attributes.Height != 2 || attributes.Format != "jpeg" || attributes.ByteSize != int64(len(data)) {
+		t.Fatalf("error expected a 4x2 jpeg of %v bytes, but got %+v", len(data), attributes)
+	}
+	if attributes.HasAlpha || attributes.ColorModel != "gray" || len(attributes.ContentHash) != 64 {
+		t.Fatalf("error expected an opaque gray image with a sha256 hash, but got %+v", attributes)
+	}
+	if attributes.EXIF["Orientation"] != int64(6) {
+		t.Fatalf("error expected orientation 6, but got %v", attributes.EXIF["Orientation"])
+	}
+	if latitude, ok := attributes.EXIF["GPSLatitude"].([]any); !ok || len(latitude) != 3 {
+		t.Fatalf("error expected 3 latitude values, but got %v", attributes.EXIF["GPSLatitude"])
+	}
+}
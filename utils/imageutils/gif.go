@@ -0,0 +1,85 @@
+package imageutils
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+)
+
+var (
+	ErrAnimationTooLarge = errors.New("animation is too large")
+	errMalformedGIF      = errors.New("malformed gif")
+)
+
+// GIFFrameCount counts the frames of a GIF by walking its blocks, without
+// decompressing any of them.
+func GIFFrameCount(data []byte) (int, error) {
+	// header and logical screen descriptor
+	if len(data) < 13 {
+		return 0, errMalformedGIF
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += colorTableSize(data[10])
+	}
+
+	frames := 0
+	for pos < len(data) {
+		var err error
+		switch data[pos] {
+		case 0x21:
+			// extension introducer, label and data sub-blocks
+			pos, err = skipSubBlocks(data, pos+2)
+		case 0x2C:
+			// image descriptor, local color table, LZW code size and data
+			if pos+10 > len(data) {
+				return 0, errMalformedGIF
+			}
+			packed := data[pos+9]
+			pos += 10
+			if packed&0x80 != 0 {
+				pos += colorTableSize(packed)
+			}
+			pos, err = skipSubBlocks(data, pos+1)
+			frames++
+		case 0x3B:
+			return frames, nil
+		default:
+			return 0, errMalformedGIF
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return frames, nil
+}
+
+// CheckAnimationSize rejects animations whose coalesced frames, full
+// canvases each, would have more than UPLOAD_MAX_PIXELS pixels in total.
+func CheckAnimationSize(frames int, config image.Config) error {
+	maxPixels := configs.GetConfig().UPLOAD_MAX_PIXELS
+	if int64(frames)*int64(config.Width)*int64(config.Height) > maxPixels {
+		return fmt.Errorf("%w: %d frames of %dx%d pixels are more than %d pixels", ErrAnimationTooLarge, frames, config.Width, config.Height, maxPixels)
+	}
+	return nil
+}
+
+func colorTableSize(packed byte) int {
+	return 3 << (packed&0x07 + 1)
+}
+
+func skipSubBlocks(data []byte, pos int) (int, error) {
+	for {
+		if pos >= len(data) {
+			return 0, errMalformedGIF
+		}
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, nil
+		}
+		pos += size
+	}
+}
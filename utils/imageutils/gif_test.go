@@ -0,0 +1,58 @@
+package imageutils_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+)
+
+// newGIF returns a GIF of the given number of 1x1 frames on a width x height
+// canvas.
+func newGIF(t *testing.T, frames int, width int, height int) []byte {
+	g := &gif.GIF{Config: image.Config{Width: width, Height: height}}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGIFFrameCount(t *testing.T) {
+	for _, frames := range []int{1, 3, 40} {
+		count, err := imageutils.GIFFrameCount(newGIF(t, frames, 10, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != frames {
+			t.Fatalf("error expected %v, but got %v", frames, count)
+		}
+	}
+	if _, err := imageutils.GIFFrameCount([]byte("GIF89a")); err == nil {
+		t.Fatal("error expected a truncated gif to be rejected")
+	}
+}
+
+func TestDecodeAnimationTooLarge(t *testing.T) {
+	// a few hundred bytes coalescing into 40 canvases of 16 million pixels
+	data := newGIF(t, 40, 4000, 4000)
+	if _, err := imageutils.Decode(bytes.NewReader(data)); !errors.Is(err, imageutils.ErrAnimationTooLarge) {
+		t.Fatalf("error expected %v, but got %v", imageutils.ErrAnimationTooLarge, err)
+	}
+
+	info, err := imageutils.Decode(bytes.NewReader(newGIF(t, 3, 10, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Animation == nil || len(info.Animation.Frames) != 3 {
+		t.Fatalf("error expected %v frames, but got %v", 3, info.Animation)
+	}
+}
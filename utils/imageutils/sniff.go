@@ -0,0 +1,32 @@
+package imageutils
+
+import "bytes"
+
+var signatures = []struct {
+	format string
+	match  func(data []byte) bool
+}{
+	{"jpeg", func(data []byte) bool { return bytes.HasPrefix(data, []byte("\xFF\xD8\xFF")) }},
+	{"png", func(data []byte) bool { return bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1A\n")) }},
+	{"gif", func(data []byte) bool {
+		return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+	}},
+	{"webp", func(data []byte) bool {
+		return len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP"))
+	}},
+	{"bmp", func(data []byte) bool { return bytes.HasPrefix(data, []byte("BM")) }},
+	{"tiff", func(data []byte) bool {
+		return bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))
+	}},
+}
+
+// Sniff returns the image format of data from its magic bytes, or an empty
+// string when data is not a supported image.
+func Sniff(data []byte) string {
+	for _, signature := range signatures {
+		if signature.match(data) {
+			return signature.format
+		}
+	}
+	return ""
+}
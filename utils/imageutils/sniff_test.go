@@ -0,0 +1,26 @@
+package imageutils_test
+
+import (
+	"testing"
+
+	"github.com/ARF-DEV/image-processing-api/utils/imageutils"
+)
+
+func TestSniff(t *testing.T) {
+	tests := map[string]string{
+		"\xFF\xD8\xFF\xE0\x00\x10JFIF":    "jpeg",
+		"\x89PNG\r\n\x1A\n\x00\x00\x00\r": "png",
+		"GIF89a\x01\x00\x01\x00":          "gif",
+		"RIFF\x24\x00\x00\x00WEBPVP8 ":    "webp",
+		"BM\x36\x00\x00\x00":              "bmp",
+		"II*\x00\x08\x00\x00\x00":         "tiff",
+		"<svg xmlns=":                     "",
+		"%PDF-1.7":                        "",
+		"RIFF\x24\x00\x00\x00WAVEfmt ":    "",
+	}
+	for data, format := range tests {
+		if got := imageutils.Sniff([]byte(data)); got != format {
+			t.Fatalf("error expected %q for %q, but got %q", format, data, got)
+		}
+	}
+}
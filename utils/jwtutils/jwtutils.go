@@ -0,0 +1,147 @@
+package jwtutils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/configs"
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// TokenConfig is what access tokens are signed and verified with.
+type TokenConfig struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+}
+
+// NewTokenConfig loads the keys of JWT_SIGNING_KEY_FILE and
+// JWT_VERIFY_KEY_FILES, or uses SECRET_KEY without them. It reads the files,
+// so it is meant to be called once at startup.
+func NewTokenConfig(cfg *configs.Config) (TokenConfig, error) {
+	keys, err := LoadKeySet(cfg.JWT_SIGNING_KEY_FILE, cfg.JWT_VERIFY_KEY_FILES, []byte(viper.GetString("SECRET_KEY")))
+	if err != nil {
+		return TokenConfig{}, err
+	}
+	return TokenConfig{
+		Keys:     keys,
+		Issuer:   cfg.JWT_ISSUER,
+		Audience: cfg.JWT_AUDIENCE,
+	}, nil
+}
+
+// Claims are the claims of an access token, SessionID identifies the login
+// the token was issued for, it is shared with the refresh tokens of that
+// login.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string   `json:"sid,omitempty"`
+	Role      string   `json:"role"`
+	Scopes    []string `json:"scopes"`
+}
+
+// Principal returns the caller authenticated by the token.
+func (c Claims) Principal() (model.Principal, error) {
+	userID, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return model.Principal{}, fmt.Errorf("invalid subject: %w", err)
+	}
+	p := model.Principal{
+		UserID:    userID,
+		Role:      c.Role,
+		Scopes:    c.Scopes,
+		TokenID:   c.ID,
+		SessionID: c.SessionID,
+	}
+	if c.ExpiresAt != nil {
+		p.ExpiresAt = c.ExpiresAt.Time
+	}
+	return p, nil
+}
+
+// NewAccessToken returns an access token of the user signed with the signing
+// key of cfg, valid for ttl, carrying the scopes of role.
+func NewAccessToken(cfg TokenConfig, userID int64, role string, sessionID string, ttl time.Duration) (string, Claims, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   strconv.FormatInt(userID, 10),
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		SessionID: sessionID,
+		Role:      role,
+		Scopes:    model.RoleScopes(role),
+	}
+
+	tokenStr, err := cfg.Keys.sign(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	return tokenStr, claims, nil
+}
+
+// ParseAccessToken verifies the signature, with the key of cfg named by its
+// kid, the issuer, audience and times of tokenStr and that it names a user,
+// a role and a token id. Errors wrap httputils.ErrAccessTokenExpired for
+// expired tokens and httputils.ErrUnauthorized for any other invalid token.
+func ParseAccessToken(cfg TokenConfig, tokenStr string) (Claims, error) {
+	claims := Claims{}
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, cfg.Keys.keyFunc,
+		jwt.WithValidMethods(cfg.Keys.methods()),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return Claims{}, fmt.Errorf("%w: %v", httputils.ErrAccessTokenExpired, err)
+		}
+		return Claims{}, fmt.Errorf("%w: %v", httputils.ErrUnauthorized, err)
+	}
+
+	if claims.ID == "" {
+		// tokens without an id can't be revoked
+		return Claims{}, fmt.Errorf("%w: token has no id", httputils.ErrUnauthorized)
+	}
+	if claims.IssuedAt == nil {
+		return Claims{}, fmt.Errorf("%w: token has no issue time", httputils.ErrUnauthorized)
+	}
+	if !model.IsRole(claims.Role) {
+		return Claims{}, fmt.Errorf("%w: invalid role %q", httputils.ErrUnauthorized, claims.Role)
+	}
+	if _, err := claims.Principal(); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", httputils.ErrUnauthorized, err)
+	}
+	return claims, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token.
+func NewRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex sha256 of token, only hashes of refresh tokens
+// are stored.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
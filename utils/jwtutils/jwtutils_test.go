@@ -0,0 +1,70 @@
+package jwtutils_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/jwtutils"
+)
+
+func TestAccessToken(t *testing.T) {
+	cfg := jwtutils.TokenConfig{Keys: jwtutils.NewHMACKeySet([]byte("secret")), Issuer: "issuer", Audience: "audience"}
+	tokenStr, claims, err := jwtutils.NewAccessToken(cfg, 7, model.ROLE_ADMIN, "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := jwtutils.ParseAccessToken(cfg, tokenStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := parsed.Principal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.UserID != 7 || principal.TokenID != claims.ID || principal.SessionID != "session" {
+		t.Fatalf("error expected %v, but got %v", claims, principal)
+	}
+	if !principal.HasRole(model.ROLE_ADMIN) || !principal.HasScope(model.SCOPE_IMAGES_READ) {
+		t.Fatalf("error expected an admin with %v, but got %v", model.SCOPE_IMAGES_READ, principal)
+	}
+
+	invalid := map[string]jwtutils.TokenConfig{
+		"key":      {Keys: jwtutils.NewHMACKeySet([]byte("other")), Issuer: cfg.Issuer, Audience: cfg.Audience},
+		"issuer":   {Keys: cfg.Keys, Issuer: "other", Audience: cfg.Audience},
+		"audience": {Keys: cfg.Keys, Issuer: cfg.Issuer, Audience: "other"},
+	}
+	for name, other := range invalid {
+		if _, err := jwtutils.ParseAccessToken(other, tokenStr); !errors.Is(err, httputils.ErrUnauthorized) {
+			t.Fatalf("error expected %v with another %v, but got %v", httputils.ErrUnauthorized, name, err)
+		}
+	}
+
+	expired, _, err := jwtutils.NewAccessToken(cfg, 7, model.ROLE_USER, "session", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtutils.ParseAccessToken(cfg, expired); !errors.Is(err, httputils.ErrAccessTokenExpired) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrAccessTokenExpired, err)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	a, err := jwtutils.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := jwtutils.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("error expected refresh tokens to differ")
+	}
+	if jwtutils.HashToken(a) != jwtutils.HashToken(a) || len(jwtutils.HashToken(a)) != 64 {
+		t.Fatalf("error expected a stable 64 character hash, but got %v", jwtutils.HashToken(a))
+	}
+}
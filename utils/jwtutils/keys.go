@@ -0,0 +1,274 @@
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet holds the key access tokens are signed with and every key they are
+// verified with, looked up by the kid header of the token. Keys are
+// identified by their RFC 7638 thumbprint, so rotating is publishing the next
+// key alongside the current one and switching the signing key once every
+// verifier fetched it.
+type KeySet struct {
+	signing *signingKey
+	keys    map[string]signingKey
+	// secret signs and verifies HS256 tokens when no signing key is set.
+	secret []byte
+}
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+	jwk     JWK
+}
+
+// JWK is the public part of a key, as published in /.well-known/jwks.json.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet returns a set signing with the RSA (RS256) or Ed25519 (EdDSA)
+// private key signing and also accepting tokens signed by the keys of
+// verify, which can be public or private keys.
+func NewKeySet(signing crypto.PrivateKey, verify ...crypto.PublicKey) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]signingKey{}}
+	key, err := newSigningKey(signing)
+	if err != nil {
+		return nil, err
+	}
+	ks.signing = &key
+	ks.keys[key.id] = key
+
+	for _, v := range verify {
+		key, err := newSigningKey(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := ks.keys[key.id]; !found {
+			ks.keys[key.id] = key
+		}
+	}
+	return ks, nil
+}
+
+// NewHMACKeySet returns a set signing and verifying HS256 tokens with secret,
+// for deployments without key files. HS256 keys can't be published.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{keys: map[string]signingKey{}, secret: secret}
+}
+
+// LoadKeySet reads the PEM keys of signingFile and verifyFiles, see
+// NewKeySet. Without signingFile, tokens are signed with secret, see
+// NewHMACKeySet.
+func LoadKeySet(signingFile string, verifyFiles []string, secret []byte) (*KeySet, error) {
+	if signingFile == "" {
+		if len(secret) == 0 {
+			return nil, errors.New("JWT_SIGNING_KEY_FILE or SECRET_KEY must be set")
+		}
+		return NewHMACKeySet(secret), nil
+	}
+
+	signing, err := readPEMKey(signingFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := signing.(crypto.Signer); !ok {
+		return nil, fmt.Errorf("%s: signing key must be a private key", signingFile)
+	}
+	var verify []crypto.PublicKey
+	for _, file := range verifyFiles {
+		if file == "" {
+			continue
+		}
+		key, err := readPEMKey(file)
+		if err != nil {
+			return nil, err
+		}
+		verify = append(verify, key)
+	}
+	return NewKeySet(signing, verify...)
+}
+
+// JWKS returns the public keys tokens are verified with, the signing key
+// first.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwks.Keys = append(jwks.Keys, key.jwk)
+	}
+	slices.SortFunc(jwks.Keys, func(a, b JWK) int {
+		if ks.signing != nil && (a.Kid == ks.signing.id) != (b.Kid == ks.signing.id) {
+			if a.Kid == ks.signing.id {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Kid, b.Kid)
+	})
+	return jwks
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.id
+	return token.SignedString(ks.signing.private)
+}
+
+// methods returns the algorithms of the keys of the set.
+func (ks *KeySet) methods() []string {
+	var methods []string
+	if ks.secret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	for _, key := range ks.keys {
+		methods = append(methods, key.method.Alg())
+	}
+	return methods
+}
+
+func (ks *KeySet) keyFunc(t *jwt.Token) (any, error) {
+	if t.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if ks.secret == nil {
+			return nil, errors.New("HS256 tokens aren't accepted")
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, found := ks.keys[kid]
+	if !found {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	// the algorithm comes from the token, it must be the one of the key
+	if key.method.Alg() != t.Method.Alg() {
+		return nil, fmt.Errorf("key %q doesn't sign %s tokens", kid, t.Method.Alg())
+	}
+	return key.public, nil
+}
+
+func newSigningKey(key any) (signingKey, error) {
+	var sk signingKey
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sk.private, sk.public = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		sk.private, sk.public = k, k.Public()
+	default:
+		sk.public = k
+	}
+
+	switch k := sk.public.(type) {
+	case *rsa.PublicKey:
+		sk.method = jwt.SigningMethodRS256
+		sk.jwk = JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		sk.method = jwt.SigningMethodEdDSA
+		sk.jwk = JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}
+	default:
+		return signingKey{}, fmt.Errorf("unsupported key type %T, only RSA and Ed25519 keys are supported", key)
+	}
+
+	id, err := thumbprint(sk.jwk)
+	if err != nil {
+		return signingKey{}, err
+	}
+	sk.id = id
+	sk.jwk.Kid = id
+	sk.jwk.Use = "sig"
+	sk.jwk.Alg = sk.method.Alg()
+	return sk, nil
+}
+
+// thumbprint returns the RFC 7638 thumbprint of the public key jwk, the hash
+// of its required members in lexicographic order.
+func thumbprint(jwk JWK) (string, error) {
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// readPEMKey reads the first PKCS#8, PKCS#1 or PKIX key of file.
+func readPEMKey(file string) (any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", file)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return key, nil
+}
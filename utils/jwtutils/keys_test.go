@@ -0,0 +1,134 @@
+package jwtutils_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/model"
+	"github.com/ARF-DEV/image-processing-api/utils/httputils"
+	"github.com/ARF-DEV/image-processing-api/utils/jwtutils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKeys, err := jwtutils.NewKeySet(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldCfg := jwtutils.TokenConfig{Keys: oldKeys, Issuer: "issuer", Audience: "audience"}
+	oldToken, _, err := jwtutils.NewAccessToken(oldCfg, 7, model.ROLE_USER, "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key signs, the old one is still accepted
+	keys, err := jwtutils.NewKeySet(newKey, oldKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := jwtutils.TokenConfig{Keys: keys, Issuer: "issuer", Audience: "audience"}
+	newToken, _, err := jwtutils.NewAccessToken(cfg, 7, model.ROLE_USER, "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := jwtutils.ParseAccessToken(cfg, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := jwtutils.ParseAccessToken(oldCfg, newToken); !errors.Is(err, httputils.ErrUnauthorized) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrUnauthorized, err)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Alg != "RS256" || jwks.Keys[1].Alg != "EdDSA" {
+		t.Fatalf("error expected the RS256 and EdDSA keys, but got %v", jwks.Keys)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &jwtutils.Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["kid"] != jwks.Keys[0].Kid {
+		t.Fatalf("error expected %v, but got %v", jwks.Keys[0].Kid, parsed.Header["kid"])
+	}
+}
+
+func TestKeySetRejectsHMAC(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := jwtutils.NewKeySet(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacCfg := jwtutils.TokenConfig{Keys: jwtutils.NewHMACKeySet([]byte("secret")), Issuer: "issuer", Audience: "audience"}
+	token, _, err := jwtutils.NewAccessToken(hmacCfg, 7, model.ROLE_USER, "session", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := jwtutils.TokenConfig{Keys: keys, Issuer: "issuer", Audience: "audience"}
+	if _, err := jwtutils.ParseAccessToken(cfg, token); !errors.Is(err, httputils.ErrUnauthorized) {
+		t.Fatalf("error expected %v, but got %v", httputils.ErrUnauthorized, err)
+	}
+	if len(hmacCfg.Keys.JWKS().Keys) != 0 {
+		t.Fatalf("error expected no published keys, but got %v", hmacCfg.Keys.JWKS().Keys)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateFile := filepath.Join(dir, "private.pem")
+	publicFile := filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := jwtutils.LoadKeySet(privateFile, []string{publicFile}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same key is only published once
+	if len(keys.JWKS().Keys) != 1 {
+		t.Fatalf("error expected %v, but got %v", 1, len(keys.JWKS().Keys))
+	}
+	if _, err := jwtutils.LoadKeySet(publicFile, nil, nil); err == nil {
+		t.Fatal("error expected a public signing key to be rejected")
+	}
+	if _, err := jwtutils.LoadKeySet("", nil, nil); err == nil {
+		t.Fatal("error expected a missing key to be rejected")
+	}
+}
@@ -0,0 +1,85 @@
+package signutils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	PARAM_USER_ID   string = "uid"
+	PARAM_EXPIRES   string = "exp"
+	PARAM_SIGNATURE string = "sig"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("signature expired")
+	ErrEmptyKey         = errors.New("signing key is empty")
+)
+
+// Sign returns query with the uid, exp and sig params added. The signature
+// covers path, every param of query, the user and the expiry, so none of
+// them can be changed without invalidating it.
+func Sign(key []byte, path string, query url.Values, userID int64, expires time.Time) (url.Values, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	signed := url.Values{}
+	for name, vals := range query {
+		signed[name] = vals
+	}
+	signed.Del(PARAM_SIGNATURE)
+	signed.Set(PARAM_USER_ID, strconv.FormatInt(userID, 10))
+	signed.Set(PARAM_EXPIRES, strconv.FormatInt(expires.Unix(), 10))
+	signed.Set(PARAM_SIGNATURE, signature(key, path, signed))
+	return signed, nil
+}
+
+// Verify checks a query made by Sign for path and returns the user it was
+// signed for. Nothing verifies with an empty key, anyone could sign with it.
+func Verify(key []byte, path string, query url.Values, now time.Time) (int64, error) {
+	if len(key) == 0 {
+		return 0, ErrEmptyKey
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get(PARAM_SIGNATURE))
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(signature(key, path, query))
+	if !hmac.Equal(sig, expected) {
+		return 0, ErrInvalidSignature
+	}
+
+	userID, err := strconv.ParseInt(query.Get(PARAM_USER_ID), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+	expires, err := strconv.ParseInt(query.Get(PARAM_EXPIRES), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+	if now.Unix() >= expires {
+		return 0, ErrExpired
+	}
+	return userID, nil
+}
+
+// signature is the HMAC-SHA256 of path and the query without sig, encoded
+// with sorted keys so the order of the params doesn't matter.
+func signature(key []byte, path string, query url.Values) string {
+	params := url.Values{}
+	for name, vals := range query {
+		if name != PARAM_SIGNATURE {
+			params[name] = vals
+		}
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path + "?" + params.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
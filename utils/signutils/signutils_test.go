@@ -0,0 +1,76 @@
+package signutils_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ARF-DEV/image-processing-api/utils/signutils"
+)
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1_700_000_000, 0)
+	path := "/signed/images/1/render"
+	query, err := signutils.Sign(key, path, url.Values{"w": {"300"}, "fmt": {"png"}}, 7, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the params can come back in any order
+	parsed, err := url.ParseQuery(query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := signutils.Verify(key, path, parsed, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != 7 {
+		t.Fatalf("error expected %v, but got %v", 7, userID)
+	}
+
+	if _, err := signutils.Verify(key, path, parsed, now.Add(time.Minute)); !errors.Is(err, signutils.ErrExpired) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrExpired, err)
+	}
+	if _, err := signutils.Verify([]byte("other"), path, parsed, now); !errors.Is(err, signutils.ErrInvalidSignature) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrInvalidSignature, err)
+	}
+	if _, err := signutils.Verify(key, "/signed/images/2/render", parsed, now); !errors.Is(err, signutils.ErrInvalidSignature) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrInvalidSignature, err)
+	}
+
+	tampered := url.Values{}
+	for name, vals := range parsed {
+		tampered[name] = vals
+	}
+	tampered.Set("w", "3000")
+	if _, err := signutils.Verify(key, path, tampered, now); !errors.Is(err, signutils.ErrInvalidSignature) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrInvalidSignature, err)
+	}
+	tampered = url.Values{}
+	for name, vals := range parsed {
+		tampered[name] = vals
+	}
+	tampered.Set(signutils.PARAM_USER_ID, "8")
+	if _, err := signutils.Verify(key, path, tampered, now); !errors.Is(err, signutils.ErrInvalidSignature) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrInvalidSignature, err)
+	}
+}
+
+func TestSignEmptyKey(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	path := "/signed/images/1/download"
+	if _, err := signutils.Sign(nil, path, url.Values{}, 7, now.Add(time.Minute)); !errors.Is(err, signutils.ErrEmptyKey) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrEmptyKey, err)
+	}
+
+	query, err := signutils.Sign([]byte("secret"), path, url.Values{}, 7, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signutils.Verify([]byte{}, path, query, now); !errors.Is(err, signutils.ErrEmptyKey) {
+		t.Fatalf("error expected %v, but got %v", signutils.ErrEmptyKey, err)
+	}
+}